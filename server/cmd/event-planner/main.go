@@ -8,21 +8,64 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	// the alpine image has no zone database, events are held in any zone
+	_ "time/tzdata"
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/config"
+	adminGetFailedEmails "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/emails/getAll"
+	adminRetryEmail "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/emails/retry"
+	adminCreateEventType "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/event_types/create"
+	adminDeleteEventType "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/event_types/delete"
+	adminUpdateEventType "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/event_types/put"
+	adminDeleteUser "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/users/delete"
+	adminGetAllUsers "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/users/getAll"
+	adminSetRole "github.com/Kazan-Strelnikova/SPDA/server/internal/http/admin/users/role"
+	calendarFeed "github.com/Kazan-Strelnikova/SPDA/server/internal/http/calendar/feed"
 	createEnrollment "github.com/Kazan-Strelnikova/SPDA/server/internal/http/enrollments/create"
 	deleteEnrollment "github.com/Kazan-Strelnikova/SPDA/server/internal/http/enrollments/delete"
+	exportEnrollments "github.com/Kazan-Strelnikova/SPDA/server/internal/http/enrollments/export"
+	getEnrollment "github.com/Kazan-Strelnikova/SPDA/server/internal/http/enrollments/get"
+	getAllEnrollments "github.com/Kazan-Strelnikova/SPDA/server/internal/http/enrollments/getAll"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/enrollments/reminders"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/enrollments/waitlist"
+	getEventTypes "github.com/Kazan-Strelnikova/SPDA/server/internal/http/event_types/getAll"
+	cancelEvent "github.com/Kazan-Strelnikova/SPDA/server/internal/http/events/cancel"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/events/create"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/events/delete"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/events/get"
 	getall "github.com/Kazan-Strelnikova/SPDA/server/internal/http/events/getAll"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/events/publish"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/events/put"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/middleware/auth"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/middleware/ratelimit"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/middleware/role"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/ping"
+	emailconfirm "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/email_confirm"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/login"
+	revokeCalendarFeed "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/calendar/delete"
+	regenerateCalendarFeed "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/calendar/post"
+	deleteMe "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/delete"
+	changeEmail "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/email"
+	getMe "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/get"
+	getNotificationSettings "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/notifications/get"
+	putNotificationSettings "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/notifications/put"
+	changePassword "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/password"
+	patchMe "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/me/patch"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/password/forgot"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/password/reset"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/refresh"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/register"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/signout"
+	signoutall "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/signout_all"
 	tokenlogin "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/token_login"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/verify"
+	verifyresend "github.com/Kazan-Strelnikova/SPDA/server/internal/http/users/verify_resend"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/log"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/mailer/filedrop"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/mailer/logmail"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/mailer/smtp"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage/postgres"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage/redis"
@@ -45,15 +88,35 @@ func main() {
 	defer storage.Close()
 
 	log.Debug(cfg.CacheAddr)
-	cache, err := redis.New(cfg.CacheAddr)
+	// A nil *redis.Client must not end up inside the interface, the service
+	// checks the cache against nil.
+	var cache service.Cache
+	redisClient, err := redis.New(cfg.CacheAddr)
 	if err != nil {
 		log.Error("error connecting to cache. proceeding without it", slog.String("err", err.Error()))
-		cache = nil
+	} else {
+		cache = redisClient
 	}
 
 	log.Info("database connection established")
 
-	service := service.New(log, storage, storage, cfg.JWTSecret, cfg.SMTPConfig, cache)
+	mailer, err := newMailer(log, cfg)
+	if err != nil {
+		log.Error("error setting up mailer", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+	defer mailer.Close()
+
+	log.Info("mail driver selected", slog.String("driver", cfg.MailConfig.Driver))
+
+	service := service.New(log, storage, storage, storage, storage, storage, cfg.TokenConfig, cfg.URLConfig, mailer, cache)
+
+	if cfg.AdminEmail != "" {
+		if err := service.PromoteAdmin(context.Background(), cfg.AdminEmail); err != nil {
+			log.Error("error promoting admin", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+	}
 
 	router := gin.Default()
 
@@ -68,21 +131,65 @@ func main() {
 		log.Warn("tracer initialization error", slog.String("err", err.Error()))
 	}
 
+	// Permission matrix. Every route is either public, open to any signed in
+	// user, or restricted to the listed roles. Ownership of a particular event
+	// is checked by the service. Public event routes still identify signed in
+	// users, who may see their own drafts.
+	authenticated := auth.New(log, service, cfg.RWTimeout)
+	identified := auth.NewOptional(log, service, cfg.RWTimeout)
+	organizers := role.New(log, user.RoleOrganizer, user.RoleAdmin)
+	admins := role.New(log, user.RoleAdmin)
+
 	router.GET("/ping", ping.New(log))
 
 	router.POST("/users/signup", register.New(log, service, cfg.RWTimeout))
 	router.POST("/users/signin", login.New(log, service, cfg.RWTimeout))
 	router.GET("/users/signin/cookie", tokenlogin.New(log, service, cfg.RWTimeout))
-
-	//TODO:
-	// add login middleware and compare emails
-	router.POST("/events", create.New(log, service, cfg.RWTimeout))
-	router.GET("/events", getall.New(log, service, cfg.RWTimeout))
-	router.GET("/events/:event_id", get.New(log, service, cfg.RWTimeout))
-	router.PUT("/events/:event_id", put.New(log, service, cfg.RWTimeout))
-	router.POST("/events/:event_id/enrollment", auth.New(log, service, cfg.RWTimeout), createEnrollment.New(log, service, cfg.RWTimeout))
-	router.DELETE("/events/:event_id/enrollment", auth.New(log, service, cfg.RWTimeout), deleteEnrollment.New(log, service, cfg.RWTimeout))
-	router.DELETE("/events/:event_id", auth.New(log, service, cfg.RWTimeout), delete.New(log, service, cfg.RWTimeout))
+	router.POST("/users/refresh", refresh.New(log, service, cfg.RWTimeout))
+	router.POST("/users/signout", authenticated, signout.New(log, service, cfg.RWTimeout))
+	router.POST("/users/signout-all", authenticated, signoutall.New(log, service, cfg.RWTimeout))
+	router.GET("/users/verify", verify.New(log, service, cfg.RWTimeout))
+	router.POST("/users/verify/resend", authenticated, verifyresend.New(log, service, cfg.RWTimeout))
+	router.POST("/users/password/forgot", ratelimit.New(log, 5, time.Hour), forgot.New(log, service, cfg.RWTimeout))
+	router.POST("/users/password/reset", ratelimit.New(log, 10, time.Hour), reset.New(log, service, cfg.RWTimeout))
+	router.GET("/users/me", authenticated, getMe.New(log, service, cfg.RWTimeout))
+	router.PATCH("/users/me", authenticated, patchMe.New(log, service, cfg.RWTimeout))
+	router.DELETE("/users/me", authenticated, deleteMe.New(log, service, cfg.RWTimeout))
+	router.POST("/users/me/password", authenticated, changePassword.New(log, service, cfg.RWTimeout))
+	router.POST("/users/me/email", authenticated, changeEmail.New(log, service, cfg.RWTimeout))
+	router.GET("/users/me/notification-settings", authenticated, getNotificationSettings.New(log, service, cfg.RWTimeout))
+	router.PUT("/users/me/notification-settings", authenticated, putNotificationSettings.New(log, service, cfg.RWTimeout))
+	router.POST("/users/me/calendar-feed", authenticated, regenerateCalendarFeed.New(log, service, cfg.RWTimeout))
+	router.DELETE("/users/me/calendar-feed", authenticated, revokeCalendarFeed.New(log, service, cfg.RWTimeout))
+	router.GET("/users/email/confirm", emailconfirm.New(log, service, cfg.RWTimeout))
+
+	router.GET("/event-types", getEventTypes.New(log, service, cfg.RWTimeout))
+
+	router.GET("/calendar/:token", calendarFeed.New(log, service, cfg.RWTimeout))
+
+	router.POST("/events", authenticated, organizers, create.New(log, service, cfg.RWTimeout))
+	router.GET("/events", identified, getall.New(log, service, cfg.RWTimeout))
+	router.GET("/events/:event_id", identified, get.New(log, service, cfg.RWTimeout))
+	router.PUT("/events/:event_id", authenticated, organizers, put.New(log, service, cfg.RWTimeout))
+	router.POST("/events/:event_id/enrollment", authenticated, createEnrollment.New(log, service, cfg.RWTimeout))
+	router.DELETE("/events/:event_id/enrollment", authenticated, deleteEnrollment.New(log, service, cfg.RWTimeout))
+	router.GET("/events/:event_id/enrollment", authenticated, getEnrollment.New(log, service, cfg.RWTimeout))
+	router.PUT("/events/:event_id/enrollment/reminders", authenticated, reminders.New(log, service, cfg.RWTimeout))
+	router.GET("/events/:event_id/waitlist", authenticated, organizers, waitlist.New(log, service, cfg.RWTimeout))
+	router.GET("/events/:event_id/enrollments", authenticated, organizers, getAllEnrollments.New(log, service, cfg.RWTimeout))
+	router.GET("/events/:event_id/enrollments/export", authenticated, organizers, exportEnrollments.New(log, service, cfg.RWTimeout))
+	router.POST("/events/:event_id/cancel", authenticated, organizers, cancelEvent.New(log, service, cfg.RWTimeout))
+	router.POST("/events/:event_id/publish", authenticated, organizers, publish.New(log, service, cfg.RWTimeout))
+	router.DELETE("/events/:event_id", authenticated, organizers, delete.New(log, service, cfg.RWTimeout))
+
+	router.GET("/admin/users", authenticated, admins, adminGetAllUsers.New(log, service, cfg.RWTimeout))
+	router.PUT("/admin/users/:user_id/role", authenticated, admins, adminSetRole.New(log, service, cfg.RWTimeout))
+	router.DELETE("/admin/users/:user_id", authenticated, admins, adminDeleteUser.New(log, service, cfg.RWTimeout))
+	router.POST("/admin/event-types", authenticated, admins, adminCreateEventType.New(log, service, cfg.RWTimeout))
+	router.PUT("/admin/event-types/:type_id", authenticated, admins, adminUpdateEventType.New(log, service, cfg.RWTimeout))
+	router.DELETE("/admin/event-types/:type_id", authenticated, admins, adminDeleteEventType.New(log, service, cfg.RWTimeout))
+	router.GET("/admin/emails/failed", authenticated, admins, adminGetFailedEmails.New(log, service, cfg.RWTimeout))
+	router.POST("/admin/emails/failed/:email_id/retry", authenticated, admins, adminRetryEmail.New(log, service, cfg.RWTimeout))
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -102,9 +209,16 @@ func main() {
 		}
 	}()
 
-	go service.Monitor(done)
+	stop := make(chan struct{})
+	go service.Monitor(stop)
+	go service.DeliverEmails(stop)
+	go service.NotifyEventChanges(stop)
+	go service.PublishScheduledEvents(stop)
+	go service.ExtendSeries(stop)
+	go service.SendPasswordResets(stop)
+
 	<-done
-	close(done)
+	close(stop)
 	log.Info("stopping the server")
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.RWTimeout)
 	defer cancel()
@@ -115,3 +229,20 @@ func main() {
 
 	log.Info("server exited properly")
 }
+
+type mailDriver interface {
+	service.Mailer
+	Close() error
+}
+
+// newMailer picks the mail driver named in the config.
+func newMailer(log *slog.Logger, cfg *config.Config) (mailDriver, error) {
+	switch cfg.MailConfig.Driver {
+	case "smtp":
+		return smtp.New(cfg.SMTPConfig, cfg.MailConfig.From, cfg.MailConfig.PoolSize), nil
+	case "file":
+		return filedrop.New(cfg.MailConfig.Dir, cfg.MailConfig.From)
+	default:
+		return logmail.New(log), nil
+	}
+}
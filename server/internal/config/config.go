@@ -23,7 +23,10 @@ type Config struct {
 	LogHost     string
 	LogPort     string
 	SMTPConfig  SMTPConfig
-	JWTSecret   string
+	MailConfig  MailConfig
+	TokenConfig TokenConfig
+	URLConfig   URLConfig
+	AdminEmail  string
 }
 
 type SMTPConfig struct {
@@ -33,6 +36,28 @@ type SMTPConfig struct {
 	Password string
 }
 
+// MailConfig selects how outgoing emails are delivered. Driver is one of
+// "smtp", "file" or "log".
+type MailConfig struct {
+	Driver   string
+	From     string
+	Dir      string
+	PoolSize int
+}
+
+type URLConfig struct {
+	Public string
+	Client string
+}
+
+type TokenConfig struct {
+	Secret          string
+	AccessTTL       time.Duration
+	RefreshTTL      time.Duration
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+}
+
 func LoadConfig() *Config {
 	if err := godotenv.Load(); err != nil {
 		log.Println("Warning: No .env file found, using system environment variables")
@@ -54,8 +79,28 @@ func LoadConfig() *Config {
 	}
 
 	SMTPPassword := os.Getenv("SMTP_PASSWORD")
+
+	// Without SMTP credentials emails are only logged, so the app runs
+	// locally without a mail server.
+	defaultDriver := "smtp"
 	if SMTPPassword == "" {
-		log.Fatal("no SMTP password provided")
+		defaultDriver = "log"
+	}
+
+	mailDriver := getEnv("MAIL_DRIVER", defaultDriver)
+	switch mailDriver {
+	case "smtp":
+		if SMTPPassword == "" {
+			log.Fatal("no SMTP password provided")
+		}
+	case "file", "log":
+	default:
+		log.Fatal("unknown mail driver")
+	}
+
+	poolSize, err := strconv.Atoi(getEnv("SMTP_POOL_SIZE", "4"))
+	if err != nil || poolSize < 1 {
+		log.Fatal("smtp pool size must be a positive number")
 	}
 
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -63,6 +108,26 @@ func LoadConfig() *Config {
 		log.Fatal("missing jwt secret")
 	}
 
+	accessTTL, err := time.ParseDuration(getEnv("ACCESS_TOKEN_TTL", "15m"))
+	if err != nil {
+		log.Fatal("error parsing duration")
+	}
+
+	refreshTTL, err := time.ParseDuration(getEnv("REFRESH_TOKEN_TTL", "720h"))
+	if err != nil {
+		log.Fatal("error parsing duration")
+	}
+
+	verificationTTL, err := time.ParseDuration(getEnv("EMAIL_VERIFICATION_TTL", "48h"))
+	if err != nil {
+		log.Fatal("error parsing duration")
+	}
+
+	resetTTL, err := time.ParseDuration(getEnv("PASSWORD_RESET_TTL", "1h"))
+	if err != nil {
+		log.Fatal("error parsing duration")
+	}
+
 	config := &Config{
 		DBHost:      getEnv("POSTGRES_HOST", "localhost"),
 		DBPort:      getEnv("POSTGRES_PORT", "5432"),
@@ -76,13 +141,30 @@ func LoadConfig() *Config {
 		Env:         getEnv("ENV", "local"),
 		LogHost:     getEnv("LOGSTASH_HOST", "logstash"),
 		LogPort:     getEnv("LOGSTASH_PORT", "5044"),
-		JWTSecret:   jwtSecret,
+		TokenConfig: TokenConfig{
+			Secret:          jwtSecret,
+			AccessTTL:       accessTTL,
+			RefreshTTL:      refreshTTL,
+			VerificationTTL: verificationTTL,
+			ResetTTL:        resetTTL,
+		},
+		URLConfig: URLConfig{
+			Public: getEnv("PUBLIC_URL", "http://localhost:8080"),
+			Client: getEnv("CLIENT_URL", "http://localhost:3000"),
+		},
+		AdminEmail: os.Getenv("ADMIN_EMAIL"),
 		SMTPConfig: SMTPConfig{
 			Host:     getEnv("SMTP_HOST", "smtp.google.com"),
 			Port:     SMPTPort,
 			Username: getEnv("SMTP_USERNAME", "zvukovat@gmail.com"),
 			Password: SMTPPassword,
 		},
+		MailConfig: MailConfig{
+			Driver:   mailDriver,
+			From:     getEnv("MAIL_FROM", getEnv("SMTP_USERNAME", "zvukovat@gmail.com")),
+			Dir:      getEnv("MAIL_DIR", "mail"),
+			PoolSize: poolSize,
+		},
 	}
 	return config
 }
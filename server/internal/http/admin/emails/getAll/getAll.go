@@ -0,0 +1,52 @@
+package getall
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminService interface {
+	ListFailedEmails(ctx context.Context, limit, offset int) ([]letter.DeadLetter, error)
+}
+
+func New(log *slog.Logger, service AdminService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		var limit, offset int
+
+		if val, exists := c.GetQuery("limit"); exists {
+			num, err := strconv.Atoi(val)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+				return
+			}
+			limit = num
+		}
+
+		if val, exists := c.GetQuery("offset"); exists {
+			num, err := strconv.Atoi(val)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+				return
+			}
+			offset = num
+		}
+
+		letters, err := service.ListFailedEmails(ctx, limit, offset)
+		if err != nil {
+			log.Error("error retrieving failed emails", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve failed emails"})
+			return
+		}
+
+		c.JSON(http.StatusOK, letters)
+	}
+}
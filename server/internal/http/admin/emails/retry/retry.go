@@ -0,0 +1,45 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminService interface {
+	RetryFailedEmail(ctx context.Context, id uuid.UUID) error
+}
+
+func New(log *slog.Logger, svc AdminService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		emailIDStr := c.Param("email_id")
+		emailID, err := uuid.Parse(emailIDStr)
+		if err != nil {
+			log.Error("invalid email ID", slog.String("email_id", emailIDStr), slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email ID"})
+			return
+		}
+
+		err = svc.RetryFailedEmail(ctx, emailID)
+		if err != nil {
+			log.Error("error re-driving email", slog.String("email_id", emailIDStr), slog.String("error", err.Error()))
+			if errors.Is(err, service.ErrEmailNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "failed email not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not re-drive email"})
+			return
+		}
+
+		c.Status(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,57 @@
+package create
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type AdminService interface {
+	CreateEventType(ctx context.Context, t event.Type) (event.Type, error)
+}
+
+// CreateTypeRequest names the type in the languages of the emails, the
+// English name is required.
+type CreateTypeRequest struct {
+	Names map[string]string `json:"names" validate:"required,dive,keys,oneof=en ru,endkeys,required,max=64"`
+}
+
+func New(log *slog.Logger, svc AdminService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		var req CreateTypeRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+			return
+		}
+
+		if req.Names["en"] == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": "the English name is required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		t, err := svc.CreateEventType(ctx, event.Type{Names: req.Names})
+		if err != nil {
+			log.Error("error creating event type", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create event type"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, t)
+	}
+}
@@ -0,0 +1,48 @@
+package delete
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminService interface {
+	DeleteEventType(ctx context.Context, id int) error
+}
+
+func New(log *slog.Logger, svc AdminService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		typeIDStr := c.Param("type_id")
+		typeID, err := strconv.Atoi(typeIDStr)
+		if err != nil {
+			log.Error("invalid type ID", slog.String("type_id", typeIDStr), slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type ID"})
+			return
+		}
+
+		err = svc.DeleteEventType(ctx, typeID)
+		if err != nil {
+			log.Error("error deleting event type", slog.String("type_id", typeIDStr), slog.String("error", err.Error()))
+			switch {
+			case errors.Is(err, service.ErrEventTypeNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "event type not found"})
+			case errors.Is(err, service.ErrEventTypeInUse):
+				c.JSON(http.StatusConflict, gin.H{"error": "event type is in use"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete event type"})
+			}
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
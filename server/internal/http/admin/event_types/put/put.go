@@ -0,0 +1,74 @@
+package put
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type AdminService interface {
+	UpdateEventType(ctx context.Context, t event.Type) error
+}
+
+// UpdateTypeRequest replaces every name of the type, the English name is
+// required.
+type UpdateTypeRequest struct {
+	Names map[string]string `json:"names" validate:"required,dive,keys,oneof=en ru,endkeys,required,max=64"`
+}
+
+func New(log *slog.Logger, svc AdminService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		typeIDStr := c.Param("type_id")
+		typeID, err := strconv.Atoi(typeIDStr)
+		if err != nil {
+			log.Error("invalid type ID", slog.String("type_id", typeIDStr), slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type ID"})
+			return
+		}
+
+		var req UpdateTypeRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+			return
+		}
+
+		if req.Names["en"] == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": "the English name is required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		t := event.Type{ID: typeID, Names: req.Names}
+
+		err = svc.UpdateEventType(ctx, t)
+		if err != nil {
+			log.Error("error updating event type", slog.String("type_id", typeIDStr), slog.String("error", err.Error()))
+			if errors.Is(err, service.ErrEventTypeNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "event type not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update event type"})
+			return
+		}
+
+		c.JSON(http.StatusOK, t)
+	}
+}
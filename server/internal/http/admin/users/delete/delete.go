@@ -0,0 +1,45 @@
+package delete
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminService interface {
+	RemoveUser(ctx context.Context, userID uuid.UUID) error
+}
+
+func New(log *slog.Logger, svc AdminService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		userIDStr := c.Param("user_id")
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			log.Error("invalid user ID", slog.String("user_id", userIDStr), slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+
+		err = svc.RemoveUser(ctx, userID)
+		if err != nil {
+			log.Error("error removing user", slog.String("user_id", userIDStr), slog.String("error", err.Error()))
+			if errors.Is(err, service.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not remove user"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,68 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type AdminService interface {
+	SetUserRole(ctx context.Context, userID uuid.UUID, role user.Role) error
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user organizer admin"`
+}
+
+func New(log *slog.Logger, svc AdminService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		userIDStr := c.Param("user_id")
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			log.Error("invalid user ID", slog.String("user_id", userIDStr), slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+
+		var req SetRoleRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err = svc.SetUserRole(ctx, userID, user.Role(req.Role))
+		if err != nil {
+			log.Error("error setting role", slog.String("user_id", userIDStr), slog.String("error", err.Error()))
+			switch {
+			case errors.Is(err, service.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			case errors.Is(err, service.ErrInvalidRole):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not set role"})
+			}
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
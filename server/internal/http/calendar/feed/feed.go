@@ -0,0 +1,49 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarService interface {
+	GetCalendarFeed(ctx context.Context, token string) ([]byte, error)
+}
+
+// New serves the personal iCalendar feed. Calendar clients cannot send
+// cookies, the secret token in the path is the only credential.
+func New(log *slog.Logger, svc CalendarService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("calendar feed request")
+
+		token := strings.TrimSuffix(c.Param("token"), ".ics")
+		if token == "" {
+			log.Error("Feed token is absent")
+			c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		data, err := svc.GetCalendarFeed(ctx, token)
+		if err != nil {
+			log.Error("Could not get calendar feed", "error", err)
+			if errors.Is(err, service.ErrFeedNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get calendar feed"})
+			return
+		}
+
+		c.Header("Cache-Control", "private, max-age=300")
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+	}
+}
@@ -0,0 +1,31 @@
+package cookies
+
+import (
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	AccessToken  = "token"
+	RefreshToken = "refresh_token"
+
+	// the refresh token is only needed by the /users/... endpoints, so it is
+	// not sent along with every other request
+	refreshPath = "/users"
+)
+
+func SetTokens(c *gin.Context, tokens user.Tokens) {
+	c.SetCookie(AccessToken, tokens.AccessToken, maxAge(tokens.AccessExpiresAt), "/", "", false, true)
+	c.SetCookie(RefreshToken, tokens.RefreshToken, maxAge(tokens.RefreshExpiresAt), refreshPath, "", false, true)
+}
+
+func Clear(c *gin.Context) {
+	c.SetCookie(AccessToken, "", -1, "/", "", false, true)
+	c.SetCookie(RefreshToken, "", -1, refreshPath, "", false, true)
+}
+
+func maxAge(expiresAt time.Time) int {
+	return int(time.Until(expiresAt).Seconds())
+}
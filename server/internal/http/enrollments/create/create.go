@@ -2,33 +2,44 @@ package create
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type AppointmentService interface {
-	SubscribeToEvent(ctx context.Context, eventId uuid.UUID, email string) (uuid.UUID, error)
+	SubscribeToEvent(ctx context.Context, eventId uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, error)
+	SubscribeToSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) ([]enrollment.Enrollment, error)
 }
 
-func New(log *slog.Logger, service AppointmentService, timeout time.Duration) func(c *gin.Context) {
+// Values of the scope query parameter. A series enrollment covers every
+// occurrence of a recurring event, including the ones to come.
+const (
+	scopeOccurrence = "occurrence"
+	scopeSeries     = "series"
+)
+
+func New(log *slog.Logger, svc AppointmentService, timeout time.Duration) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		log.Info("create appointment request")
 
-		emailAny, ok := c.Get("email")
+		userIDAny, ok := c.Get("user_id")
 		if !ok {
-			log.Error("email does not exist on the context")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "email does not exist on the context"})
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
 			return
 		}
 
-		email, ok := emailAny.(string)
+		userID, ok := userIDAny.(uuid.UUID)
 		if !ok {
-			log.Error("email is not of proper format")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "email is not of proper format"})
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
 			return
 		}
 
@@ -39,18 +50,73 @@ func New(log *slog.Logger, service AppointmentService, timeout time.Duration) fu
 			return
 		}
 
+		scope := c.DefaultQuery("scope", scopeOccurrence)
+		if scope != scopeOccurrence && scope != scopeSeries {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be occurrence or series"})
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		id, err := service.SubscribeToEvent(ctx, eventId, email)
+		if scope == scopeSeries {
+			enrollments, err := svc.SubscribeToSeries(ctx, eventId, userID)
+			if err != nil {
+				switch {
+				case errors.Is(err, service.ErrNotVerified):
+					log.Error("Could not subscribe to series, email is not verified")
+					c.JSON(http.StatusForbidden, gin.H{"error": "email address is not verified"})
+				case errors.Is(err, service.ErrEventNotFound):
+					log.Error("Could not subscribe to series, event not found")
+					c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+				case errors.Is(err, service.ErrNotRecurring):
+					log.Error("Could not subscribe to series, event is not recurring")
+					c.JSON(http.StatusConflict, gin.H{"error": "event is not recurring"})
+				case errors.Is(err, service.ErrAlreadyEnrolled):
+					log.Error("Could not subscribe to series, already enrolled")
+					c.JSON(http.StatusConflict, gin.H{"error": "already enrolled in the series"})
+				default:
+					log.Error("Could not subscribe to series")
+					c.JSON(http.StatusBadRequest, gin.H{"error": "could not subscribe to series"})
+				}
+				return
+			}
+
+			resp := make([]gin.H, 0, len(enrollments))
+			for _, enrlmnt := range enrollments {
+				resp = append(resp, gin.H{"id": enrlmnt.Id, "event_id": enrlmnt.EventId, "status": enrlmnt.Status})
+			}
+
+			log.Info("create series appointment request succeeded")
+
+			c.JSON(http.StatusCreated, resp)
+			return
+		}
+
+		enrlmnt, err := svc.SubscribeToEvent(ctx, eventId, userID)
 		if err != nil {
-			log.Error("Could not subscribe to event")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "could not subscribe to event"})
+			switch {
+			case errors.Is(err, service.ErrNotVerified):
+				log.Error("Could not subscribe to event, email is not verified")
+				c.JSON(http.StatusForbidden, gin.H{"error": "email address is not verified"})
+			case errors.Is(err, service.ErrEventNotFound):
+				log.Error("Could not subscribe to event, event not found")
+				c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			case errors.Is(err, service.ErrEventClosed):
+				log.Error("Could not subscribe to event, event is closed")
+				c.JSON(http.StatusConflict, gin.H{"error": "event does not take enrollments"})
+			case errors.Is(err, service.ErrAlreadyEnrolled):
+				log.Error("Could not subscribe to event, already enrolled")
+				c.JSON(http.StatusConflict, gin.H{"error": "already enrolled in the event"})
+			default:
+				log.Error("Could not subscribe to event")
+				c.JSON(http.StatusBadRequest, gin.H{"error": "could not subscribe to event"})
+			}
 			return
 		}
 
 		log.Info("create appointment request succeeded")
 
-		c.JSON(http.StatusCreated, gin.H{"id": id})
+		c.JSON(http.StatusCreated, gin.H{"id": enrlmnt.Id, "status": enrlmnt.Status})
 	}
 }
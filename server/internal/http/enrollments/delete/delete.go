@@ -2,33 +2,43 @@ package delete
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type EventService interface {
-	UnsibscribeFromEvent(ctx context.Context, eventId uuid.UUID, email string) error
+	UnsibscribeFromEvent(ctx context.Context, eventId uuid.UUID, userID uuid.UUID) error
+	UnsubscribeFromSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) error
 }
 
-func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *gin.Context) {
+// Values of the scope query parameter. Leaving a series withdraws from every
+// occurrence that has not started and from the ones to come.
+const (
+	scopeOccurrence = "occurrence"
+	scopeSeries     = "series"
+)
+
+func New(log *slog.Logger, svc EventService, timeout time.Duration) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		log.Info("delete appointment request")
 
-		emailAny, ok := c.Get("email")
+		userIDAny, ok := c.Get("user_id")
 		if !ok {
-			log.Error("email does not exist on the context")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "email does not exist on the context"})
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
 			return
 		}
 
-		email, ok := emailAny.(string)
+		userID, ok := userIDAny.(uuid.UUID)
 		if !ok {
-			log.Error("email is not of proper format")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "email is not of proper format"})
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
 			return
 		}
 
@@ -39,10 +49,39 @@ func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *
 			return
 		}
 
+		scope := c.DefaultQuery("scope", scopeOccurrence)
+		if scope != scopeOccurrence && scope != scopeSeries {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be occurrence or series"})
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		err = service.UnsibscribeFromEvent(ctx, eventId, email)
+		if scope == scopeSeries {
+			err = svc.UnsubscribeFromSeries(ctx, eventId, userID)
+			if err != nil {
+				switch {
+				case errors.Is(err, service.ErrEventNotFound):
+					c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+				case errors.Is(err, service.ErrNotRecurring):
+					c.JSON(http.StatusConflict, gin.H{"error": "event is not recurring"})
+				case errors.Is(err, service.ErrNotEnrolled):
+					c.JSON(http.StatusNotFound, gin.H{"error": "not enrolled in the series"})
+				default:
+					log.Error("Could not unsubscribe from series")
+					c.JSON(http.StatusBadRequest, gin.H{"error": "could not unsubscribe from series"})
+				}
+				return
+			}
+
+			log.Info("delete series appointment request succeeded")
+
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		err = svc.UnsibscribeFromEvent(ctx, eventId, userID)
 		if err != nil {
 			log.Error("Could not unsubscribe from event")
 			c.JSON(http.StatusBadRequest, gin.H{"error": "could not Unsubscribe from event"})
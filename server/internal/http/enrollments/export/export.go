@@ -0,0 +1,134 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AttendeeService interface {
+	ExportAttendees(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID, fn func(enrollment.Attendee) error) error
+}
+
+var header = []string{"enrollment_id", "user_id", "name", "last_name", "email", "status", "position", "enrolled_at"}
+
+// cell keeps a value the user typed from being run as a formula by the
+// spreadsheet the file is opened in, by prefixing it with a quote.
+func cell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// New streams the enrollments of the event as CSV. The response headers are
+// written together with the first row, so access errors are still reported
+// as JSON.
+func New(log *slog.Logger, svc AttendeeService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("export enrollments request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
+		eventID, err := uuid.Parse(c.Param("event_id"))
+		if err != nil {
+			log.Error("invalid event id", slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		w := csv.NewWriter(c.Writer)
+		started := false
+
+		start := func() error {
+			started = true
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="enrollments-%s.csv"`, eventID))
+			c.Status(http.StatusOK)
+			return w.Write(header)
+		}
+
+		err = svc.ExportAttendees(ctx, userID, userRole, eventID, func(a enrollment.Attendee) error {
+			if !started {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+
+			position := ""
+			if a.Position > 0 {
+				position = strconv.Itoa(a.Position)
+			}
+
+			return w.Write([]string{
+				a.EnrollmentID.String(),
+				a.UserID.String(),
+				cell(a.Name),
+				cell(a.LastName),
+				cell(a.Email),
+				string(a.Status),
+				position,
+				a.CreatedAt.Format(time.RFC3339),
+			})
+		})
+		if err != nil {
+			log.Error("could not export enrollments", slog.String("error", err.Error()))
+			if started {
+				w.Flush()
+				c.Abort()
+				return
+			}
+			switch {
+			case errors.Is(err, service.ErrEventNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			case errors.Is(err, service.ErrForbidden):
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can export the enrollments"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not export enrollments"})
+			}
+			return
+		}
+
+		if !started {
+			if err := start(); err != nil {
+				log.Error("could not write csv header", slog.String("error", err.Error()))
+				return
+			}
+		}
+
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Error("could not write csv", slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package get
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type EnrollmentService interface {
+	GetEnrollment(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, int, error)
+}
+
+func New(log *slog.Logger, svc EnrollmentService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("get enrollment request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		eventID, err := uuid.Parse(c.Param("event_id"))
+		if err != nil {
+			log.Error("invalid event id", slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		enrlmnt, position, err := svc.GetEnrollment(ctx, eventID, userID)
+		if err != nil {
+			if errors.Is(err, service.ErrNotEnrolled) {
+				log.Error("user is not enrolled in the event")
+				c.JSON(http.StatusNotFound, gin.H{"error": "not enrolled in the event"})
+				return
+			}
+			log.Error("could not get enrollment", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get enrollment"})
+			return
+		}
+
+		response := gin.H{
+			"id":         enrlmnt.Id,
+			"status":     enrlmnt.Status,
+			"created_at": enrlmnt.CreatedAt,
+			// null means the reminder settings of the user apply
+			"reminder_offsets": enrlmnt.ReminderOffsets,
+		}
+		if enrlmnt.Status == enrollment.StatusWaitlist {
+			response["position"] = position
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
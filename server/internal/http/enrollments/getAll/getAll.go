@@ -0,0 +1,88 @@
+package getall
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AttendeeService interface {
+	ListAttendees(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID, limit, offset int) ([]enrollment.Attendee, error)
+}
+
+func New(log *slog.Logger, svc AttendeeService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("get enrollments request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
+		eventID, err := uuid.Parse(c.Param("event_id"))
+		if err != nil {
+			log.Error("invalid event id", slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+			return
+		}
+
+		var limit, offset int
+
+		if val, exists := c.GetQuery("limit"); exists {
+			num, err := strconv.Atoi(val)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+				return
+			}
+			limit = num
+		}
+
+		if val, exists := c.GetQuery("offset"); exists {
+			num, err := strconv.Atoi(val)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+				return
+			}
+			offset = num
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		attendees, err := svc.ListAttendees(ctx, userID, userRole, eventID, limit, offset)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrEventNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			case errors.Is(err, service.ErrForbidden):
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can see the enrollments"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get enrollments"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"enrollments": attendees})
+	}
+}
@@ -0,0 +1,86 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type ReminderService interface {
+	SetEnrollmentReminders(ctx context.Context, eventID, userID uuid.UUID, offsets []int) ([]int, error)
+}
+
+// SetRemindersRequest overrides the reminder offsets for one event. A null
+// reminder_offsets removes the override.
+type SetRemindersRequest struct {
+	ReminderOffsets []int `json:"reminder_offsets" validate:"omitempty,max=5,dive,min=1,max=43200"`
+}
+
+func New(log *slog.Logger, svc ReminderService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("set enrollment reminders request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		eventID, err := uuid.Parse(c.Param("event_id"))
+		if err != nil {
+			log.Error("invalid event id", slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+			return
+		}
+
+		var req SetRemindersRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		offsets, err := svc.SetEnrollmentReminders(ctx, eventID, userID, req.ReminderOffsets)
+		if err != nil {
+			log.Error("Could not set enrollment reminders", "error", err)
+			switch {
+			case errors.Is(err, service.ErrInvalidOffsets):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reminder offsets"})
+			case errors.Is(err, service.ErrNotEnrolled):
+				c.JSON(http.StatusNotFound, gin.H{"error": "not enrolled in the event"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not set reminders"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"reminder_offsets": offsets})
+	}
+}
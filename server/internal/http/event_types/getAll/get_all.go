@@ -0,0 +1,31 @@
+package getall
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/gin-gonic/gin"
+)
+
+type EventTypeService interface {
+	GetEventTypes(ctx context.Context) ([]event.Type, error)
+}
+
+func New(log *slog.Logger, svc EventTypeService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		types, err := svc.GetEventTypes(ctx)
+		if err != nil {
+			log.Error("error retrieving event types", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve event types"})
+			return
+		}
+
+		c.JSON(http.StatusOK, types)
+	}
+}
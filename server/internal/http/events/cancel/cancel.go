@@ -0,0 +1,95 @@
+package cancel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type EventService interface {
+	CancelEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID, reason *string) (event.Event, error)
+}
+
+type CancelEventRequest struct {
+	Reason *string `json:"reason" validate:"omitempty,min=1,max=1000"`
+}
+
+func New(log *slog.Logger, svc EventService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("cancel event request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
+		eventID, err := uuid.Parse(c.Param("event_id"))
+		if err != nil {
+			log.Error("Invalid event id", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		// the body is optional
+		var req CancelEventRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		evt, err := svc.CancelEvent(ctx, userID, userRole, eventID, req.Reason)
+		if err != nil {
+			log.Error("Could not cancel event", "error", err)
+			switch {
+			case errors.Is(err, service.ErrEventNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			case errors.Is(err, service.ErrForbidden):
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can cancel the event"})
+			case errors.Is(err, service.ErrEventCancelled):
+				c.JSON(http.StatusConflict, gin.H{"error": "event is already cancelled"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not cancel event"})
+			}
+			return
+		}
+
+		log.Info("cancel event request succeeded")
+
+		c.JSON(http.StatusOK, evt)
+	}
+}
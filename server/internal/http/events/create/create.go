@@ -2,39 +2,70 @@ package create
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/paulmach/orb"
 )
 
 type EventService interface {
-	CreateEvent(ctx context.Context, evt event.Event) (event.Event, error)
+	CreateEvent(ctx context.Context, creatorID uuid.UUID, evt event.Event) (event.Event, error)
+	CreateSeries(ctx context.Context, creatorID uuid.UUID, evt event.Event, rule string, exdates []time.Time) (event.Event, error)
 }
 
 type CreateEventRequest struct {
-	Title        string `json:"title" binding:"required" validate:"required"`
-	Type         int    `json:"type" binding:"required" validate:"required"`
-	Date         string `json:"date" binding:"required" validate:"required"`
-	TotalSeats   int    `json:"total_seats" binding:"required,min=1" validate:"required,min=1"`
-	CreatorEmail string `json:"creator_email" binding:"required,email" validate:"required,email"`
-	Location     struct {
+	Title      string `json:"title" binding:"required" validate:"required"`
+	Type       int    `json:"type" binding:"required" validate:"required"`
+	Date       string `json:"date" binding:"required" validate:"required"`
+	TotalSeats int    `json:"total_seats" binding:"required,min=1" validate:"required,min=1"`
+	Location   struct {
 		Latitude  string `json:"latitude" binding:"required" validate:"required"`
 		Longitude string `json:"longitude" binding:"required" validate:"required"`
 	} `json:"location" binding:"required" validate:"required"`
 	HasUnlimitedSeats string  `json:"has_unlimited_seats" validate:"required,oneofci='true' 'false'"`
 	Description       *string `json:"description,omitempty"`
+	// EndsAt is an hour after the date by default. TimeZone is an IANA name
+	// such as Europe/Moscow, UTC by default.
+	EndsAt   *string `json:"ends_at,omitempty"`
+	TimeZone string  `json:"time_zone" validate:"omitempty,timezone"`
+	// Status is published by default. Drafts are seen only by the creator.
+	Status    string  `json:"status" validate:"omitempty,oneof=draft published"`
+	PublishAt *string `json:"publish_at,omitempty"`
+	// RRule makes the event recurring, date is its first occurrence.
+	// ExDates are the starts of the rule to skip.
+	RRule   *string  `json:"rrule,omitempty"`
+	ExDates []string `json:"exdates,omitempty"`
+	// Tags are free-form, compared without regard to case.
+	Tags []string `json:"tags" validate:"max=10,dive,max=32"`
 }
 
-func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *gin.Context) {
+func New(log *slog.Logger, svc EventService, timeout time.Duration) func(c *gin.Context) {
 	validate := validator.New()
 	return func(c *gin.Context) {
 		var req CreateEventRequest
 
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": "Invalid request data", "details": err.Error()})
 			return
@@ -65,23 +96,98 @@ func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *
 			return
 		}
 
+		var endsAt time.Time
+		if req.EndsAt != nil {
+			endsAt, err = time.Parse(time.RFC3339, *req.EndsAt)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid ends_at format, expected RFC3339"})
+				return
+			}
+
+			if !endsAt.After(eventDate) {
+				c.JSON(400, gin.H{"error": "ends_at must be after date"})
+				return
+			}
+		}
+
+		status := event.Status(req.Status)
+
+		// a scheduled event is a draft until its time comes
+		var publishAt *time.Time
+		if req.PublishAt != nil {
+			t, err := time.Parse(time.RFC3339, *req.PublishAt)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid publish_at format, expected RFC3339"})
+				return
+			}
+
+			if status == event.StatusPublished {
+				c.JSON(400, gin.H{"error": "publish_at is only allowed for drafts"})
+				return
+			}
+
+			if !t.After(time.Now()) || !t.Before(eventDate) {
+				c.JSON(400, gin.H{"error": "publish_at must be in the future and before the event"})
+				return
+			}
+
+			status = event.StatusDraft
+			publishAt = &t
+		}
+
 		evt := event.Event{
 			Title:             req.Title,
 			Type:              req.Type,
 			Date:              eventDate,
+			EndsAt:            endsAt,
+			TimeZone:          req.TimeZone,
 			TotalSeats:        req.TotalSeats,
 			AvailableSeats:    req.TotalSeats,
-			CreatorEmail:      req.CreatorEmail,
 			Location:          location,
 			HasUnlimitedSeats: req.HasUnlimitedSeats,
 			Description:       req.Description,
+			Status:            status,
+			PublishAt:         publishAt,
+			Tags:              req.Tags,
+		}
+
+		if req.RRule == nil && len(req.ExDates) > 0 {
+			c.JSON(400, gin.H{"error": "exdates are only allowed with rrule"})
+			return
+		}
+
+		exdates := make([]time.Time, 0, len(req.ExDates))
+		for _, exdate := range req.ExDates {
+			t, err := time.Parse(time.RFC3339, exdate)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid exdates format, expected RFC3339"})
+				return
+			}
+			exdates = append(exdates, t)
 		}
 
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		createdEvent, err := service.CreateEvent(ctx, evt)
+		var createdEvent event.Event
+		if req.RRule != nil {
+			createdEvent, err = svc.CreateSeries(ctx, userID, evt, *req.RRule, exdates)
+		} else {
+			createdEvent, err = svc.CreateEvent(ctx, userID, evt)
+		}
 		if err != nil {
+			if errors.Is(err, service.ErrNotVerified) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+				return
+			}
+			if errors.Is(err, service.ErrInvalidRule) {
+				c.JSON(400, gin.H{"error": "Invalid rrule", "details": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrEventTypeNotFound) {
+				c.JSON(400, gin.H{"error": "Unknown event type"})
+				return
+			}
 			c.JSON(500, gin.H{"error": "Error creating event", "details": err.Error()})
 			return
 		}
@@ -0,0 +1,54 @@
+package create
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestCreateEventRequestTagLimits(t *testing.T) {
+	validate := validator.New()
+
+	tags := func(n int) []string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = strings.Repeat("t", i+1)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		ok   bool
+	}{
+		{name: "none", tags: nil, ok: true},
+		{name: "ten tags", tags: tags(10), ok: true},
+		{name: "eleven tags", tags: tags(11), ok: false},
+		{name: "32 letters", tags: []string{strings.Repeat("a", 32)}, ok: true},
+		{name: "33 letters", tags: []string{strings.Repeat("a", 33)}, ok: false},
+		{name: "32 cyrillic letters", tags: []string{strings.Repeat("я", 32)}, ok: true},
+		{name: "33 cyrillic letters", tags: []string{strings.Repeat("я", 33)}, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CreateEventRequest{
+				Title:             "Go meetup",
+				Type:              1,
+				Date:              "2025-06-01T18:00:00Z",
+				TotalSeats:        10,
+				HasUnlimitedSeats: "false",
+				Tags:              tt.tags,
+			}
+			req.Location.Latitude = "55.79"
+			req.Location.Longitude = "49.12"
+
+			err := validate.Struct(req)
+			if (err == nil) != tt.ok {
+				t.Errorf("validate(%d tags) error = %v, want ok %v", len(tt.tags), err, tt.ok)
+			}
+		})
+	}
+}
@@ -2,20 +2,23 @@ package delete
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"log/slog"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type EventService interface {
-	DeleteEvent(ctx context.Context, eventID uuid.UUID) error
+	DeleteEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) error
 }
 
-func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *gin.Context) {
+func New(log *slog.Logger, svc EventService, timeout time.Duration) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
@@ -28,15 +31,30 @@ func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *
 			return
 		}
 
-		email, ok := c.Get("email")
+		userIDAny, ok := c.Get("user_id")
 		if !ok {
-			log.Error("email does not exist on the context")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "email does not exist on the context"})
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
 			return
 		}
 
-		err = service.DeleteEvent(context.WithValue(ctx, "email", email), eventID)
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
+		err = svc.DeleteEvent(ctx, userID, userRole, eventID)
 		if err != nil {
+			if errors.Is(err, service.ErrForbidden) {
+				log.Error("user may not delete the event", slog.String("event_id", eventIDStr))
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can delete the event"})
+				return
+			}
 			log.Error("event not found", slog.String("event_id", eventIDStr), slog.String("error", err.Error()))
 			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
 			return
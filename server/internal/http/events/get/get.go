@@ -8,12 +8,13 @@ import (
 	"log/slog"
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type EventService interface {
-	GetEvent(ctx context.Context, eventID uuid.UUID) (event.Event, error)
+	GetEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) (event.Event, error)
 }
 
 func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *gin.Context) {
@@ -29,7 +30,13 @@ func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *
 			return
 		}
 
-		evt, err := service.GetEvent(ctx, eventID)
+		// anonymous users are welcome, they just do not see drafts
+		userIDAny, _ := c.Get("user_id")
+		userID, _ := userIDAny.(uuid.UUID)
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
+		evt, err := service.GetEvent(ctx, userID, userRole, eventID)
 		if err != nil {
 			log.Error("event not found", slog.String("event_id", eventIDStr), slog.String("error", err.Error()))
 			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
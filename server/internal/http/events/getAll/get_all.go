@@ -2,106 +2,254 @@ package getall
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/paulmach/orb"
 )
 
 type EventService interface {
-	GetAllEvents(
-		ctx context.Context,
-		limit, offset *int,
-		eventType *int,
-		creatorEmail *string,
-		before, after *time.Time,
-		location *orb.Point,
-		radius *float64,
-		visitorEmail *string,
-	) ([]event.Event, error)
+	GetAllEvents(ctx context.Context, userID uuid.UUID, role user.Role, filter event.Filter) (event.Page, error)
 }
 
-func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *gin.Context) {
+func New(log *slog.Logger, svc EventService, timeout time.Duration) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		var (
-			limit, offset *int
-			eventType     *int
-			creatorEmail  *string
-			before, after *time.Time
-			location      *orb.Point
-			radius        *float64
-			visitorEmail  *string
-		)
-
-		// Parse query params
-		if val, exists := c.GetQuery("limit"); exists {
-			if num, err := strconv.Atoi(val); err == nil {
-				limit = &num
-			}
+		filter, problems := parseFilter(c)
+		if len(problems) > 0 {
+			log.Error("invalid query parameters", slog.Any("problems", problems))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters", "details": problems})
+			return
 		}
 
-		if val, exists := c.GetQuery("offset"); exists {
-			if num, err := strconv.Atoi(val); err == nil {
-				offset = &num
+		// only needed to list the user's own drafts
+		userIDAny, _ := c.Get("user_id")
+		userID, _ := userIDAny.(uuid.UUID)
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
+		page, err := svc.GetAllEvents(ctx, userID, userRole, filter)
+		if err != nil {
+			log.Error("error retrieving events", slog.String("error", err.Error()))
+			if errors.Is(err, service.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "drafts and enrollments are only listed for their owner"})
+				return
 			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve events"})
+			return
 		}
 
-		if val, exists := c.GetQuery("type"); exists {
-			if num, err := strconv.Atoi(val); err == nil {
-				eventType = &num
-			}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// parseFilter reads the filter from the query, along with a description of
+// every malformed parameter.
+func parseFilter(c *gin.Context) (event.Filter, []string) {
+	var problems []string
+
+	filter := event.Filter{
+		Sort:  event.SortDate,
+		Limit: event.DefaultLimit,
+	}
+
+	parseTime := func(name string) *time.Time {
+		val, exists := c.GetQuery(name)
+		if !exists {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			problems = append(problems, name+" must be an RFC3339 time")
+			return nil
+		}
+		return &t
+	}
+
+	parseID := func(name string) *uuid.UUID {
+		val, exists := c.GetQuery(name)
+		if !exists {
+			return nil
 		}
+		id, err := uuid.Parse(val)
+		if err != nil {
+			problems = append(problems, name+" must be a UUID")
+			return nil
+		}
+		return &id
+	}
 
-		if val, exists := c.GetQuery("creator_email"); exists {
-			creatorEmail = &val
+	parseFloat := func(name string) *float64 {
+		val, exists := c.GetQuery(name)
+		if !exists {
+			return nil
 		}
+		num, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			problems = append(problems, name+" must be a number")
+			return nil
+		}
+		return &num
+	}
 
-		if val, exists := c.GetQuery("before"); exists {
-			if t, err := time.Parse(time.RFC3339, val); err == nil {
-				before = &t
-			}
+	if val, exists := c.GetQuery("limit"); exists {
+		num, err := strconv.Atoi(val)
+		if err != nil || num < 1 || num > event.MaxLimit {
+			problems = append(problems, fmt.Sprintf("limit must be an integer from 1 to %d", event.MaxLimit))
+		} else {
+			filter.Limit = num
 		}
+	}
 
-		if val, exists := c.GetQuery("after"); exists {
-			if t, err := time.Parse(time.RFC3339, val); err == nil {
-				after = &t
-			}
+	// search results are the best matches first, unless sorted otherwise
+	if val, exists := c.GetQuery("q"); exists {
+		if strings.TrimSpace(val) == "" {
+			problems = append(problems, "q must not be empty")
+		} else {
+			filter.Query = &val
+			filter.Sort, filter.Desc = event.SortRelevance, true
 		}
+	}
 
-		if latStr, latExists := c.GetQuery("lat"); latExists {
-			if lonStr, lonExists := c.GetQuery("lon"); lonExists {
-				if lat, err1 := strconv.ParseFloat(latStr, 64); err1 == nil {
-					if lon, err2 := strconv.ParseFloat(lonStr, 64); err2 == nil {
-						if val, exists := c.GetQuery("radius"); exists {
-							if num, err := strconv.ParseFloat(val, 64); err == nil {
-								loc := orb.Point{lon, lat}
-								location = &loc
-								radius = &num
-							}
-						}
-					}
-				}
-			}
+	if val, exists := c.GetQuery("sort"); exists {
+		filter.Sort, filter.Desc = event.Sort(val), false
+		if !filter.Sort.IsValid() {
+			problems = append(problems, "sort must be one of date, distance, seats, created_at, relevance")
 		}
+	}
 
-		if val, exists := c.GetQuery("visitor_email"); exists {
-			visitorEmail = &val
+	if val, exists := c.GetQuery("order"); exists {
+		filter.Desc = false
+		switch val {
+		case "asc":
+		case "desc":
+			filter.Desc = true
+		default:
+			problems = append(problems, "order must be asc or desc")
 		}
+	}
 
-		events, err := service.GetAllEvents(ctx, limit, offset, eventType, creatorEmail, before, after, location, radius, visitorEmail)
+	if val, exists := c.GetQuery("with_total"); exists {
+		withTotal, err := strconv.ParseBool(val)
 		if err != nil {
-			log.Error("error retrieving events", slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve events"})
-			return
+			problems = append(problems, "with_total must be true or false")
+		}
+		filter.WithTotal = withTotal
+	}
+
+	if val, exists := c.GetQuery("cursor"); exists {
+		var cursor event.Cursor
+		switch {
+		case cursor.UnmarshalText([]byte(val)) != nil:
+			problems = append(problems, "cursor is malformed")
+		case cursor.Sort != filter.Sort || cursor.Desc != filter.Desc:
+			problems = append(problems, "cursor belongs to a different sort or order")
+		default:
+			filter.Cursor = &cursor
+		}
+	}
+
+	// types and tags repeat or are separated by commas
+	for _, val := range splitQuery(c, "type") {
+		num, err := strconv.Atoi(val)
+		if err != nil {
+			problems = append(problems, "type must be a list of integers")
+			break
+		}
+		filter.Types = append(filter.Types, num)
+	}
+
+	if tags := splitQuery(c, "tag"); len(tags) > 0 {
+		filter.Tags = event.NormalizeTags(tags)
+	}
+
+	filter.CreatorID = parseID("creator_id")
+	filter.VisitorID = parseID("visitor_id")
+	filter.Before = parseTime("before")
+	filter.After = parseTime("after")
+
+	lat, lon := parseFloat("lat"), parseFloat("lon")
+	filter.Radius = parseFloat("radius")
+
+	switch {
+	case (lat == nil) != (lon == nil):
+		problems = append(problems, "lat and lon must be given together")
+	case lat != nil && (*lat < -90 || *lat > 90):
+		problems = append(problems, "lat must be from -90 to 90")
+	case lon != nil && (*lon < -180 || *lon > 180):
+		problems = append(problems, "lon must be from -180 to 180")
+	case lat != nil:
+		loc := orb.Point{*lon, *lat}
+		filter.Location = &loc
+	}
+
+	if filter.Radius != nil && (*filter.Radius <= 0 || filter.Location == nil) {
+		problems = append(problems, "radius must be positive and needs lat and lon")
+	}
+
+	if filter.Sort == event.SortRelevance && filter.Query == nil {
+		problems = append(problems, "sorting by relevance needs q")
+	}
+
+	if filter.Sort == event.SortDistance && filter.Location == nil {
+		problems = append(problems, "sorting by distance needs lat and lon")
+	}
+
+	if val, exists := c.GetQuery("status"); exists {
+		s := event.Status(val)
+		if !s.IsValid() {
+			problems = append(problems, "status must be one of draft, published, cancelled, completed")
+		} else {
+			filter.Status = &s
 		}
+	}
 
-		c.JSON(http.StatusOK, events)
+	// events overlapping the window, or happening now
+	filter.From = parseTime("overlaps_from")
+	filter.To = parseTime("overlaps_to")
+
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		problems = append(problems, "overlaps_to must not be before overlaps_from")
+	}
+
+	if val, exists := c.GetQuery("happening_now"); exists {
+		now, err := strconv.ParseBool(val)
+		switch {
+		case err != nil:
+			problems = append(problems, "happening_now must be true or false")
+		case now && (filter.From != nil || filter.To != nil):
+			problems = append(problems, "happening_now cannot be combined with overlaps_from or overlaps_to")
+		case now:
+			t := time.Now()
+			filter.From, filter.To = &t, &t
+		}
+	}
+
+	return filter, problems
+}
+
+// splitQuery returns the values of the query parameter, which may repeat or
+// hold several values separated by commas.
+func splitQuery(c *gin.Context, name string) []string {
+	var values []string
+	for _, val := range c.QueryArray(name) {
+		for _, v := range strings.Split(val, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
 	}
+	return values
 }
@@ -0,0 +1,99 @@
+package getall
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestParseFilterCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cursorText := func(c event.Cursor) string {
+		text, err := c.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText: %v", err)
+		}
+		return string(text)
+	}
+
+	id := uuid.New()
+	byDate := event.Cursor{Sort: event.SortDate, Key: "2025-06-01 18:00:00+00", ID: id}
+	bySeatsDesc := event.Cursor{Sort: event.SortSeats, Desc: true, Key: "5", ID: id}
+	byRelevance := event.Cursor{Sort: event.SortRelevance, Desc: true, Key: "0.1", ID: id}
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		want    *event.Cursor
+		problem string
+	}{
+		{
+			name:  "default sort",
+			query: url.Values{"cursor": {cursorText(byDate)}},
+			want:  &byDate,
+		},
+		{
+			name:  "same sort and order",
+			query: url.Values{"sort": {"seats"}, "order": {"desc"}, "cursor": {cursorText(bySeatsDesc)}},
+			want:  &bySeatsDesc,
+		},
+		{
+			name:  "search sorted by relevance by default",
+			query: url.Values{"q": {"go"}, "cursor": {cursorText(byRelevance)}},
+			want:  &byRelevance,
+		},
+		{
+			name:    "malformed",
+			query:   url.Values{"cursor": {"garbage"}},
+			problem: "cursor is malformed",
+		},
+		{
+			name:    "other sort",
+			query:   url.Values{"sort": {"created_at"}, "cursor": {cursorText(byDate)}},
+			problem: "cursor belongs to a different sort or order",
+		},
+		{
+			name:    "other order",
+			query:   url.Values{"sort": {"seats"}, "cursor": {cursorText(bySeatsDesc)}},
+			problem: "cursor belongs to a different sort or order",
+		},
+		{
+			name:    "search cursor without the search",
+			query:   url.Values{"cursor": {cursorText(byRelevance)}},
+			problem: "cursor belongs to a different sort or order",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/events?"+tt.query.Encode(), nil)
+
+			filter, problems := parseFilter(c)
+
+			if tt.problem != "" {
+				if !slices.Contains(problems, tt.problem) {
+					t.Errorf("problems = %v, want %q", problems, tt.problem)
+				}
+				if filter.Cursor != nil {
+					t.Errorf("cursor %+v was accepted", *filter.Cursor)
+				}
+				return
+			}
+
+			if len(problems) > 0 {
+				t.Fatalf("unexpected problems %v", problems)
+			}
+			if filter.Cursor == nil || *filter.Cursor != *tt.want {
+				t.Errorf("cursor = %v, want %+v", filter.Cursor, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type EventService interface {
+	PublishEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) (event.Event, error)
+}
+
+func New(log *slog.Logger, svc EventService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("publish event request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
+		eventID, err := uuid.Parse(c.Param("event_id"))
+		if err != nil {
+			log.Error("Invalid event id", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		evt, err := svc.PublishEvent(ctx, userID, userRole, eventID)
+		if err != nil {
+			log.Error("Could not publish event", "error", err)
+			switch {
+			case errors.Is(err, service.ErrEventNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			case errors.Is(err, service.ErrForbidden):
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can publish the event"})
+			case errors.Is(err, service.ErrNotDraft):
+				c.JSON(http.StatusConflict, gin.H{"error": "event is not a draft"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not publish event"})
+			}
+			return
+		}
+
+		log.Info("publish event request succeeded")
+
+		c.JSON(http.StatusOK, evt)
+	}
+}
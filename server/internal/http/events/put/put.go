@@ -2,12 +2,15 @@ package put
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -15,28 +18,67 @@ import (
 )
 
 type EventService interface {
-	UpdateEvent(ctx context.Context, evt event.Event) error
+	UpdateEvent(ctx context.Context, userID uuid.UUID, role user.Role, evt event.Event, notify bool) (event.Event, error)
+	UpdateFollowing(ctx context.Context, userID uuid.UUID, role user.Role, evt event.Event, notify bool) (event.Event, error)
 }
 
+// Values of the notification query parameter. Silent edits are not emailed
+// to the enrolled users.
+const (
+	notificationNotify = "notify"
+	notificationSilent = "silent"
+)
+
+// Values of the scope query parameter. An edit of an occurrence of a
+// recurring event applies to it alone or to it and every later one.
+const (
+	scopeOccurrence = "occurrence"
+	scopeFollowing  = "following"
+)
+
 type CreateEventRequest struct {
-	Title        string `json:"title" binding:"required" validate:"required"`
-	Type         int    `json:"type" binding:"required" validate:"required"`
-	Date         string `json:"date" binding:"required" validate:"required"`
-	TotalSeats   int    `json:"total_seats" binding:"required,min=1" validate:"required,min=1"`
-	CreatorEmail string `json:"creator_email" binding:"required,email" validate:"required,email"`
-	Location     struct {
+	Title      string `json:"title" binding:"required" validate:"required"`
+	Type       int    `json:"type" binding:"required" validate:"required"`
+	Date       string `json:"date" binding:"required" validate:"required"`
+	TotalSeats int    `json:"total_seats" binding:"required,min=1" validate:"required,min=1"`
+	Location   struct {
 		Latitude  string `json:"latitude" binding:"required" validate:"required"`
 		Longitude string `json:"longitude" binding:"required" validate:"required"`
 	} `json:"location" binding:"required" validate:"required"`
 	HasUnlimitedSeats string  `json:"has_unlimited_seats" validate:"required,oneofci='true' 'false'"`
 	Description       *string `json:"description,omitempty"`
+	// EndsAt and TimeZone are kept as they were when left out, an event
+	// that is moved keeps its duration.
+	EndsAt   *string `json:"ends_at,omitempty"`
+	TimeZone string  `json:"time_zone" validate:"omitempty,timezone"`
+	// PublishAt reschedules a draft, leaving it out unschedules it.
+	PublishAt *string `json:"publish_at,omitempty"`
+	// Tags are kept as they were when left out, an empty list removes them.
+	Tags []string `json:"tags" validate:"max=10,dive,max=32"`
 }
 
-func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *gin.Context) {
+func New(log *slog.Logger, svc EventService, timeout time.Duration) func(c *gin.Context) {
 	validate := validator.New()
 	return func(c *gin.Context) {
 		var req CreateEventRequest
 
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		role, _ := c.Get("role")
+		userRole, _ := role.(user.Role)
+
 		eventIDStr := c.Param("event_id")
 		eventID, err := uuid.Parse(eventIDStr)
 		if err != nil {
@@ -45,6 +87,18 @@ func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *
 			return
 		}
 
+		notification := c.DefaultQuery("notification", notificationNotify)
+		if notification != notificationNotify && notification != notificationSilent {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "notification must be notify or silent"})
+			return
+		}
+
+		scope := c.DefaultQuery("scope", scopeOccurrence)
+		if scope != scopeOccurrence && scope != scopeFollowing {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be occurrence or following"})
+			return
+		}
+
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": "Invalid request data", "details": err.Error()})
 			return
@@ -75,28 +129,89 @@ func New(log *slog.Logger, service EventService, timeout time.Duration) func(c *
 			return
 		}
 
+		var endsAt time.Time
+		if req.EndsAt != nil {
+			endsAt, err = time.Parse(time.RFC3339, *req.EndsAt)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid ends_at format, expected RFC3339"})
+				return
+			}
+
+			if !endsAt.After(eventDate) {
+				c.JSON(400, gin.H{"error": "ends_at must be after date"})
+				return
+			}
+		}
+
+		var publishAt *time.Time
+		if req.PublishAt != nil {
+			t, err := time.Parse(time.RFC3339, *req.PublishAt)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid publish_at format, expected RFC3339"})
+				return
+			}
+
+			if !t.After(time.Now()) || !t.Before(eventDate) {
+				c.JSON(400, gin.H{"error": "publish_at must be in the future and before the event"})
+				return
+			}
+
+			publishAt = &t
+		}
+
 		evt := event.Event{
 			ID:                eventID,
 			Title:             req.Title,
 			Type:              req.Type,
 			Date:              eventDate,
+			EndsAt:            endsAt,
+			TimeZone:          req.TimeZone,
 			TotalSeats:        req.TotalSeats,
 			AvailableSeats:    req.TotalSeats,
-			CreatorEmail:      req.CreatorEmail,
 			Location:          location,
 			HasUnlimitedSeats: req.HasUnlimitedSeats,
 			Description:       req.Description,
+			PublishAt:         publishAt,
+			Tags:              req.Tags,
 		}
 
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		err = service.UpdateEvent(ctx, evt)
+		update := svc.UpdateEvent
+		if scope == scopeFollowing {
+			update = svc.UpdateFollowing
+		}
+
+		updatedEvent, err := update(ctx, userID, userRole, evt, notification == notificationNotify)
 		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrForbidden):
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can edit the event"})
+				return
+			case errors.Is(err, service.ErrEventNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+				return
+			case errors.Is(err, service.ErrEventCancelled):
+				c.JSON(http.StatusConflict, gin.H{"error": "cancelled events cannot be edited"})
+				return
+			case errors.Is(err, service.ErrNotDraft):
+				c.JSON(http.StatusConflict, gin.H{"error": "only drafts can be scheduled"})
+				return
+			case errors.Is(err, service.ErrNotRecurring):
+				c.JSON(http.StatusConflict, gin.H{"error": "event is not recurring"})
+				return
+			case errors.Is(err, service.ErrInvalidRule):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid edit of the series", "details": err.Error()})
+				return
+			case errors.Is(err, service.ErrEventTypeNotFound):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown event type"})
+				return
+			}
 			c.JSON(422, gin.H{"error": "Error creating event", "details": err.Error()})
 			return
 		}
 
-		c.JSON(200, evt)
+		c.JSON(200, updatedEvent)
 	}
 }
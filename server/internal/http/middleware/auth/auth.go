@@ -6,38 +6,72 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type AuthService interface {
-	LoginByToken(ctx context.Context, token string) (user.User, error)
+	LoginByToken(ctx context.Context, token string) (user.User, uuid.UUID, error)
 }
 
 func New(log *slog.Logger, service AuthService, timeout time.Duration) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		log.Info("attempt of cookie login through middleware")
 
-		token, err := c.Cookie("token")
+		token, err := c.Cookie(cookies.AccessToken)
 		if err != nil {
 			log.Error("Missing authentication token", "error", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 			return
 		}
 
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		usr, err := service.LoginByToken(ctx, token)
+		usr, sessionID, err := service.LoginByToken(ctx, token)
 		if err != nil {
 			log.Error("Invalid authentication token", "error", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
 
-		log.Info("cookie login successful", slog.String("email", usr.Email))
+		log.Info("cookie login successful", slog.String("user_id", usr.ID.String()))
 
+		c.Set("user_id", usr.ID)
 		c.Set("email", usr.Email)
+		c.Set("role", usr.Role)
+		c.Set("session_id", sessionID)
+
+		c.Next()
+	}
+}
+
+// NewOptional identifies the user like New when the request has a valid
+// token, and lets anonymous requests through otherwise.
+func NewOptional(log *slog.Logger, service AuthService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(cookies.AccessToken)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		usr, sessionID, err := service.LoginByToken(ctx, token)
+		if err != nil {
+			log.Warn("ignoring invalid authentication token", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Set("user_id", usr.ID)
+		c.Set("email", usr.Email)
+		c.Set("role", usr.Role)
+		c.Set("session_id", sessionID)
 
 		c.Next()
 	}
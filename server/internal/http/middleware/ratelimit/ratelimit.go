@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// New allows at most limit requests per client IP in every window. The
+// counters live in memory, so every replica limits on its own.
+func New(log *slog.Logger, limit int, period time.Duration) func(c *gin.Context) {
+	var (
+		mu      sync.Mutex
+		windows = make(map[string]*window)
+		swept   = time.Now()
+	)
+
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		now := time.Now()
+
+		mu.Lock()
+
+		if now.Sub(swept) > period {
+			for key, w := range windows {
+				if now.Sub(w.start) > period {
+					delete(windows, key)
+				}
+			}
+			swept = now
+		}
+
+		w, ok := windows[ip]
+		if !ok || now.Sub(w.start) > period {
+			w = &window{start: now}
+			windows[ip] = w
+		}
+		w.count++
+		exceeded := w.count > limit
+
+		mu.Unlock()
+
+		if exceeded {
+			log.Warn("rate limit exceeded", slog.String("ip", ip), slog.String("path", c.FullPath()))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
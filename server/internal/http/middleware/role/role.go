@@ -0,0 +1,39 @@
+package role
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/gin-gonic/gin"
+)
+
+// New lets the request through only if the role set by the auth middleware
+// is one of the allowed roles. It has to be registered after auth.New.
+func New(log *slog.Logger, allowed ...user.Role) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		roleAny, ok := c.Get("role")
+		if !ok {
+			log.Error("role does not exist on the context")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		role, ok := roleAny.(user.Role)
+		if !ok {
+			log.Error("role is not of proper format")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		for _, r := range allowed {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+
+		log.Warn("access denied", slog.String("role", string(role)), slog.String("path", c.FullPath()))
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	}
+}
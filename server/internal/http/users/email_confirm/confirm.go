@@ -0,0 +1,50 @@
+package emailconfirm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type UserService interface {
+	ConfirmEmailChange(ctx context.Context, token string) error
+}
+
+func New(log *slog.Logger, svc UserService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("email change confirmation request")
+
+		token, ok := c.GetQuery("token")
+		if !ok || token == "" {
+			log.Error("Missing confirmation token")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing confirmation token"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := svc.ConfirmEmailChange(ctx, token)
+		if err != nil {
+			log.Error("Email change failed", "error", err)
+			switch {
+			case errors.Is(err, service.ErrInvalidToken), errors.Is(err, service.ErrUserNotFound):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+			case errors.Is(err, service.ErrUserExists):
+				c.JSON(http.StatusConflict, gin.H{"error": "Email is already taken"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not change email"})
+			}
+			return
+		}
+
+		log.Info("email change succeeded")
+
+		c.JSON(http.StatusOK, gin.H{"status": "email changed"})
+	}
+}
@@ -6,13 +6,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
 type UserService interface {
-	Login(ctx context.Context, email, password string) (user.User, string, error)
+	Login(ctx context.Context, email, password string) (user.User, user.Tokens, error)
 }
 
 type LoginRequest struct {
@@ -47,7 +48,7 @@ func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *g
 		log = log.With(slog.String("email", req.Email))
 		log.Info("attempt to log in user")
 
-		user, token, err := service.Login(ctx, req.Email, req.Password)
+		user, tokens, err := service.Login(ctx, req.Email, req.Password)
 		if err != nil {
 			log.Error("Login failed", "error", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
@@ -56,7 +57,7 @@ func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *g
 
 		log.Info("login succeeded")
 
-		c.SetCookie("token", token, 3600*24*365, "/", "", false, true)
+		cookies.SetTokens(c, tokens)
 
 		c.JSON(http.StatusOK, gin.H{
 			"user": user,
@@ -0,0 +1,55 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CalendarService interface {
+	RegenerateCalendarFeed(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+func New(log *slog.Logger, svc CalendarService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("regenerate calendar feed request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		link, err := svc.RegenerateCalendarFeed(ctx, userID)
+		if err != nil {
+			log.Error("Could not regenerate calendar feed", "error", err)
+			if errors.Is(err, service.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not regenerate calendar feed"})
+			return
+		}
+
+		log.Info("regenerate calendar feed request succeeded")
+
+		c.JSON(http.StatusCreated, gin.H{"url": link})
+	}
+}
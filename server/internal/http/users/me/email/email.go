@@ -0,0 +1,80 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type UserService interface {
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, password, newEmail string) error
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+func New(log *slog.Logger, svc UserService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("change email request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		var req ChangeEmailRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := svc.RequestEmailChange(ctx, userID, req.Password, req.NewEmail)
+		if err != nil {
+			log.Error("Could not request email change", "error", err)
+			switch {
+			case errors.Is(err, service.ErrWrongPassword):
+				c.JSON(http.StatusForbidden, gin.H{"error": "wrong password"})
+			case errors.Is(err, service.ErrUserExists):
+				c.JSON(http.StatusConflict, gin.H{"error": "email is already taken"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not request email change"})
+			}
+			return
+		}
+
+		log.Info("change email request succeeded")
+
+		c.Status(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,48 @@
+package get
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UserService interface {
+	GetProfile(ctx context.Context, userID uuid.UUID) (user.User, error)
+}
+
+func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("get profile request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		usr, err := service.GetProfile(ctx, userID)
+		if err != nil {
+			log.Error("Could not get profile", "error", err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"user": usr})
+	}
+}
@@ -0,0 +1,82 @@
+package put
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type SettingsService interface {
+	UpdateNotificationSettings(ctx context.Context, userID uuid.UUID, settings user.NotificationSettings) (user.NotificationSettings, error)
+}
+
+type UpdateSettingsRequest struct {
+	ReminderOffsets []int `json:"reminder_offsets" validate:"required,max=5,dive,min=1,max=43200"`
+}
+
+func New(log *slog.Logger, svc SettingsService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("update notification settings request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		var req UpdateSettingsRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		settings, err := svc.UpdateNotificationSettings(ctx, userID, user.NotificationSettings{
+			ReminderOffsets: req.ReminderOffsets,
+		})
+		if err != nil {
+			log.Error("Could not update notification settings", "error", err)
+			switch {
+			case errors.Is(err, service.ErrInvalidOffsets):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reminder offsets"})
+			case errors.Is(err, service.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update notification settings"})
+			}
+			return
+		}
+
+		log.Info("update notification settings request succeeded")
+
+		c.JSON(http.StatusOK, settings)
+	}
+}
@@ -0,0 +1,91 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type UserService interface {
+	ChangePassword(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID, oldPassword, newPassword string) error
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+func New(log *slog.Logger, svc UserService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("change password request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		sessionIDAny, ok := c.Get("session_id")
+		if !ok {
+			log.Error("session id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session id does not exist on the context"})
+			return
+		}
+
+		sessionID, ok := sessionIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("session id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session id is not of proper format"})
+			return
+		}
+
+		var req ChangePasswordRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := svc.ChangePassword(ctx, userID, sessionID, req.OldPassword, req.NewPassword)
+		if err != nil {
+			log.Error("Could not change password", "error", err)
+			if errors.Is(err, service.ErrWrongPassword) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "wrong password"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not change password"})
+			return
+		}
+
+		log.Info("change password request succeeded")
+
+		c.Status(http.StatusNoContent)
+	}
+}
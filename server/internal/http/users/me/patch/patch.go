@@ -0,0 +1,81 @@
+package patch
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type UserService interface {
+	UpdateProfile(ctx context.Context, userID uuid.UUID, name, lastName, locale, timeZone *string) (user.User, error)
+}
+
+type UpdateProfileRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=2,max=200"`
+	LastName *string `json:"last_name" validate:"omitempty,min=2,max=200"`
+	Locale   *string `json:"locale" validate:"omitempty,oneof=en ru"`
+	// TimeZone is an IANA name, an empty one removes the zone
+	TimeZone *string `json:"time_zone" validate:"omitempty,timezone"`
+}
+
+func New(log *slog.Logger, svc UserService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("update profile request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		var req UpdateProfileRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data", "details": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		usr, err := svc.UpdateProfile(ctx, userID, req.Name, req.LastName, req.Locale, req.TimeZone)
+		if err != nil {
+			log.Error("Could not update profile", "error", err)
+			if errors.Is(err, service.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update profile"})
+			return
+		}
+
+		log.Info("update profile request succeeded")
+
+		c.JSON(http.StatusOK, gin.H{"user": usr})
+	}
+}
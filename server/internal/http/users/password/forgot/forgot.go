@@ -0,0 +1,55 @@
+package forgot
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type UserService interface {
+	ForgotPassword(ctx context.Context, email string) error
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("forgot password request")
+
+		var req ForgotPasswordRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := service.ForgotPassword(ctx, req.Email)
+		if err != nil {
+			log.Error("Forgot password failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not request password reset"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"status": "if the address is registered, a reset letter has been sent",
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package reset
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type UserService interface {
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+func New(log *slog.Logger, svc UserService, timeout time.Duration) func(c *gin.Context) {
+	validate := validator.New()
+
+	return func(c *gin.Context) {
+		log.Info("reset password request")
+
+		var req ResetPasswordRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Error("Invalid request format", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Error("Validation failed", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := svc.ResetPassword(ctx, req.Token, req.Password)
+		if err != nil {
+			log.Error("Reset password failed", "error", err)
+			if errors.Is(err, service.ErrInvalidToken) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset password"})
+			return
+		}
+
+		log.Info("reset password request succeeded")
+
+		cookies.Clear(c)
+
+		c.Status(http.StatusNoContent)
+	}
+}
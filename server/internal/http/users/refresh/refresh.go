@@ -0,0 +1,49 @@
+package refresh
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/gin-gonic/gin"
+)
+
+type UserService interface {
+	RefreshTokens(ctx context.Context, refreshToken string) (user.User, user.Tokens, error)
+}
+
+func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		log.Info("refresh tokens request")
+
+		refreshToken, err := c.Cookie(cookies.RefreshToken)
+		if err != nil {
+			log.Error("Missing refresh token", "error", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		usr, tokens, err := service.RefreshTokens(ctx, refreshToken)
+		if err != nil {
+			log.Error("Refresh failed", "error", err)
+			cookies.Clear(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		log.Info("refresh succeeded", slog.String("email", usr.Email))
+
+		cookies.SetTokens(c, tokens)
+
+		c.JSON(http.StatusOK, gin.H{
+			"user": usr,
+		})
+	}
+}
@@ -6,13 +6,15 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
 type UserService interface {
-	Register(ctx context.Context, usr user.User) (string, error)
+	Register(ctx context.Context, usr user.User) (user.Tokens, error)
 }
 
 type RegisterRequest struct {
@@ -20,6 +22,7 @@ type RegisterRequest struct {
 	LastName string `json:"last_name" validate:"required,min=2"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
+	Locale   string `json:"locale" validate:"omitempty,oneof=en ru"`
 }
 
 func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *gin.Context) {
@@ -47,6 +50,7 @@ func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *g
 			LastName: req.LastName,
 			Email:    req.Email,
 			Password: req.Password,
+			Locale:   string(letter.ParseLocale(req.Locale)),
 		}
 
 		log = log.With(slog.String("email", req.Email))
@@ -55,7 +59,7 @@ func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *g
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		token, err := service.Register(ctx, usr)
+		tokens, err := service.Register(ctx, usr)
 		if err != nil {
 			log.Error("Registration failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not register user"})
@@ -66,7 +70,7 @@ func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *g
 
 		usr.Password = ""
 
-		c.SetCookie("token", token, 3600, "/", "", false, true)
+		cookies.SetTokens(c, tokens)
 
 		c.JSON(http.StatusCreated, gin.H{
 			"user": usr,
@@ -0,0 +1,52 @@
+package signout
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UserService interface {
+	SignOut(ctx context.Context, sessionID uuid.UUID) error
+}
+
+func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("sign out request")
+
+		sessionIDAny, ok := c.Get("session_id")
+		if !ok {
+			log.Error("session id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session id does not exist on the context"})
+			return
+		}
+
+		sessionID, ok := sessionIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("session id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session id is not of proper format"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := service.SignOut(ctx, sessionID)
+		if err != nil {
+			log.Error("Could not sign out", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not sign out"})
+			return
+		}
+
+		log.Info("sign out request succeeded")
+
+		cookies.Clear(c)
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,52 @@
+package signoutall
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UserService interface {
+	SignOutAll(ctx context.Context, userID uuid.UUID) error
+}
+
+func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("sign out from all sessions request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := service.SignOutAll(ctx, userID)
+		if err != nil {
+			log.Error("Could not sign out", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not sign out"})
+			return
+		}
+
+		log.Info("sign out from all sessions request succeeded")
+
+		cookies.Clear(c)
+
+		c.Status(http.StatusNoContent)
+	}
+}
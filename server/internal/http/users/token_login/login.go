@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/http/cookies"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type UserService interface {
-	LoginByToken(ctx context.Context, token string) (user.User, error)
+	LoginByToken(ctx context.Context, token string) (user.User, uuid.UUID, error)
 }
 
 func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *gin.Context) {
@@ -19,7 +21,7 @@ func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *g
 
 		log.Info("attempt of cookie login")
 
-		token, err := c.Cookie("token")
+		token, err := c.Cookie(cookies.AccessToken)
 		if err != nil {
 			log.Error("Missing authentication token", "error", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
@@ -29,7 +31,7 @@ func New(log *slog.Logger, service UserService, timeout time.Duration) func(c *g
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
-		usr, err := service.LoginByToken(ctx, token)
+		usr, _, err := service.LoginByToken(ctx, token)
 		if err != nil {
 			log.Error("Invalid authentication token", "error", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
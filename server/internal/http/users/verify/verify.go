@@ -0,0 +1,48 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type UserService interface {
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+func New(log *slog.Logger, svc UserService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("email verification request")
+
+		token, ok := c.GetQuery("token")
+		if !ok || token == "" {
+			log.Error("Missing verification token")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing verification token"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := svc.VerifyEmail(ctx, token)
+		if err != nil {
+			log.Error("Verification failed", "error", err)
+			switch {
+			case errors.Is(err, service.ErrInvalidToken), errors.Is(err, service.ErrUserNotFound):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify email"})
+			}
+			return
+		}
+
+		log.Info("email verification succeeded")
+
+		c.JSON(http.StatusOK, gin.H{"status": "verified"})
+	}
+}
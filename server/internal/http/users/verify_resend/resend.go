@@ -0,0 +1,55 @@
+package verifyresend
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UserService interface {
+	ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error
+}
+
+func New(log *slog.Logger, svc UserService, timeout time.Duration) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		log.Info("resend verification email request")
+
+		userIDAny, ok := c.Get("user_id")
+		if !ok {
+			log.Error("user id does not exist on the context")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id does not exist on the context"})
+			return
+		}
+
+		userID, ok := userIDAny.(uuid.UUID)
+		if !ok {
+			log.Error("user id is not of proper format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user id is not of proper format"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		err := svc.ResendVerificationEmail(ctx, userID)
+		if err != nil {
+			log.Error("Could not resend verification email", "error", err)
+			if errors.Is(err, service.ErrVerified) {
+				c.JSON(http.StatusConflict, gin.H{"error": "email address is already verified"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send verification email"})
+			return
+		}
+
+		log.Info("resend verification email request succeeded")
+
+		c.Status(http.StatusAccepted)
+	}
+}
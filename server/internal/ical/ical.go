@@ -0,0 +1,170 @@
+// Package ical writes iCalendar (RFC 5545) objects for the events, so that
+// calendar clients can add them without retyping the details.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Method string
+
+const (
+	// MethodRequest adds the event to the calendar or updates it there.
+	MethodRequest Method = "REQUEST"
+	// MethodCancel removes the event from the calendar.
+	MethodCancel Method = "CANCEL"
+	// MethodPublish is used for calendars that are only read, like feeds.
+	MethodPublish Method = "PUBLISH"
+)
+
+const (
+	prodID    = "-//SPDA//Event Planner//EN"
+	uidDomain = "spda"
+	// lines longer than this many octets are folded
+	maxLineLength = 75
+	timeFormat    = "20060102T150405Z"
+)
+
+type Person struct {
+	Name  string
+	Email string
+}
+
+type Event struct {
+	UID string
+	// Sequence has to grow with every change, otherwise calendar clients
+	// ignore the update.
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Lat, Lon    float64
+	HasGeo      bool
+	Organizer   Person
+	Attendees   []Person
+	Cancelled   bool
+}
+
+type Calendar struct {
+	Method Method
+	Name   string
+	Events []Event
+}
+
+// UID is the identifier of the event in calendars. It never changes, so every
+// letter about the event refers to the same calendar entry.
+func UID(eventID uuid.UUID) string {
+	return fmt.Sprintf("%s@%s", eventID, uidDomain)
+}
+
+// Encode returns the calendar as a text/calendar document.
+func (c Calendar) Encode() []byte {
+	w := &writer{}
+
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", prodID)
+	w.line("CALSCALE", "GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD", string(c.Method))
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME", escape(c.Name))
+	}
+
+	stamp := time.Now().UTC().Format(timeFormat)
+
+	for _, evt := range c.Events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", evt.UID)
+		w.line("SEQUENCE", fmt.Sprint(evt.Sequence))
+		w.line("DTSTAMP", stamp)
+		w.line("DTSTART", evt.Start.UTC().Format(timeFormat))
+		if !evt.End.IsZero() {
+			w.line("DTEND", evt.End.UTC().Format(timeFormat))
+		}
+		w.line("SUMMARY", escape(evt.Summary))
+		if evt.Description != "" {
+			w.line("DESCRIPTION", escape(evt.Description))
+		}
+		if evt.Location != "" {
+			w.line("LOCATION", escape(evt.Location))
+		}
+		if evt.HasGeo {
+			w.line("GEO", fmt.Sprintf("%f;%f", evt.Lat, evt.Lon))
+		}
+		if evt.Organizer.Email != "" {
+			w.line("ORGANIZER;CN="+quote(evt.Organizer.Name), "mailto:"+evt.Organizer.Email)
+		}
+		for _, attendee := range evt.Attendees {
+			w.line("ATTENDEE;CN="+quote(attendee.Name)+";ROLE=REQ-PARTICIPANT", "mailto:"+attendee.Email)
+		}
+		if evt.Cancelled {
+			w.line("STATUS", "CANCELLED")
+		} else {
+			w.line("STATUS", "CONFIRMED")
+		}
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+
+	return w.buf.Bytes()
+}
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folded to maxLineLength octets without
+// splitting UTF-8 sequences.
+func (w *writer) line(name, value string) {
+	l := name + ":" + value
+
+	// continuation lines start with a space, which counts too
+	limit := maxLineLength
+	for len(l) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(l[cut]) {
+			cut--
+		}
+		w.buf.WriteString(l[:cut])
+		w.buf.WriteString("\r\n ")
+		l = l[cut:]
+		limit = maxLineLength - 1
+	}
+
+	w.buf.WriteString(l)
+	w.buf.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// escape makes a TEXT value safe, so that user input cannot add properties.
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// quote makes a parameter value safe. Double quotes are not allowed inside
+// quoted values at all, so they are dropped.
+func quote(s string) string {
+	s = strings.NewReplacer(`"`, "", "\r", "", "\n", " ").Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,61 @@
+package filedrop
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/mailer"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+)
+
+// Mailer writes every message to its own .eml file in a directory, where it
+// can be opened with any mail client.
+type Mailer struct {
+	dir  string
+	from string
+}
+
+func New(dir, from string) (*Mailer, error) {
+	const op = "mailer.filedrop.New"
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return &Mailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+func (m *Mailer) Send(ctx context.Context, msg letter.Message) error {
+	const op = "mailer.filedrop.Send"
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	f, err := os.Create(filepath.Join(m.dir, name))
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer f.Close()
+
+	if _, err = mailer.Compose(m.from, msg).WriteTo(f); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+func (m *Mailer) Close() error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package logmail
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+)
+
+// Mailer only logs the messages it is given. Nothing is delivered.
+type Mailer struct {
+	log *slog.Logger
+}
+
+func New(log *slog.Logger) *Mailer {
+	return &Mailer{
+		log: log.With(slog.String("op", "mailer.logmail.Send")),
+	}
+}
+
+func (m *Mailer) Send(ctx context.Context, msg letter.Message) error {
+	m.log.Info(
+		"email not delivered, log mail driver in use",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+	)
+	m.log.Debug("email body", slog.String("html", msg.HTML))
+
+	return nil
+}
+
+func (m *Mailer) Close() error {
+	return nil
+}
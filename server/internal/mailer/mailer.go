@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"io"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"gopkg.in/gomail.v2"
+)
+
+// Compose builds the MIME message that the drivers deliver or store. Letters
+// with a plain-text version are sent as multipart/alternative, the HTML part
+// last so that clients prefer it.
+func Compose(from string, msg letter.Message) *gomail.Message {
+	m := gomail.NewMessage()
+
+	m.SetHeader("From", from)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	if msg.Text != "" {
+		m.SetBody("text/plain", msg.Text)
+		m.AddAlternative("text/html", msg.HTML)
+	} else {
+		m.SetBody("text/html", msg.HTML)
+	}
+
+	for _, a := range msg.Attachments {
+		data := a.Data
+		m.Attach(a.Name,
+			gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+		)
+	}
+
+	return m
+}
@@ -0,0 +1,163 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/config"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/mailer"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"gopkg.in/gomail.v2"
+)
+
+// idleTimeout is how long a connection may stay unused before it is closed
+// instead of reused. Mail servers drop idle clients after a few minutes.
+const idleTimeout = 30 * time.Second
+
+type conn struct {
+	sc       gomail.SendCloser
+	lastUsed time.Time
+}
+
+// Mailer delivers messages over SMTP and keeps up to size connections open
+// between messages.
+type Mailer struct {
+	dialer *gomail.Dialer
+	from   string
+
+	slots chan struct{}
+	idle  chan *conn
+
+	closeOnce sync.Once
+}
+
+func New(cfg config.SMTPConfig, from string, size int) *Mailer {
+	return &Mailer{
+		dialer: gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password),
+		from:   from,
+		slots:  make(chan struct{}, size),
+		idle:   make(chan *conn, size),
+	}
+}
+
+func (m *Mailer) Send(ctx context.Context, msg letter.Message) error {
+	const op = "mailer.smtp.Send"
+
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	select {
+	case m.slots <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("op: %s, err: %v", op, ctx.Err())
+	}
+	defer func() { <-m.slots }()
+
+	c, err := m.acquire()
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	body := &body{msg: mailer.Compose(m.from, msg)}
+
+	err = c.sc.Send(from.Address, []string{to.Address}, body)
+	if err != nil && !body.written && isConnectionError(err) {
+		// The server may have closed a pooled connection. Nothing was
+		// delivered yet, so retry once on a fresh one.
+		_ = c.sc.Close()
+
+		c.sc, err = m.dialer.Dial()
+		if err != nil {
+			return fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		err = c.sc.Send(from.Address, []string{to.Address}, body)
+	}
+	if err != nil {
+		// a refused or interrupted transaction leaves the connection in an
+		// unknown state
+		_ = c.sc.Close()
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	c.lastUsed = time.Now()
+	m.idle <- c
+
+	return nil
+}
+
+// body is the message handed to the server. It notes when the server accepted
+// DATA and asked for the message, after that point the letter may have been
+// delivered and must not be sent again.
+type body struct {
+	msg     *gomail.Message
+	written bool
+}
+
+func (b *body) WriteTo(w io.Writer) (int64, error) {
+	b.written = true
+	return b.msg.WriteTo(w)
+}
+
+// isConnectionError reports whether err means the connection failed rather
+// than the server refused the message. Replies of the server are returned to
+// the caller as they are, except for 421, which the server sends when it
+// closes the connection.
+func isConnectionError(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code == 421
+	}
+
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.As(err, &netErr)
+}
+
+// acquire returns a pooled connection if a fresh one is available and dials a
+// new one otherwise. The caller must hold a slot.
+func (m *Mailer) acquire() (*conn, error) {
+	for {
+		select {
+		case c := <-m.idle:
+			if time.Since(c.lastUsed) < idleTimeout {
+				return c, nil
+			}
+			_ = c.sc.Close()
+		default:
+			sc, err := m.dialer.Dial()
+			if err != nil {
+				return nil, err
+			}
+			return &conn{sc: sc}, nil
+		}
+	}
+}
+
+func (m *Mailer) Close() error {
+	m.closeOnce.Do(func() {
+		for {
+			select {
+			case c := <-m.idle:
+				_ = c.sc.Close()
+			default:
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"testing"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"closed by server", io.EOF, true},
+		{"network", &net.OpError{Op: "write", Net: "tcp", Err: errors.New("broken pipe")}, true},
+		{"wrapped network", fmt.Errorf("mail: %w", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("reset")}), true},
+		{"closing channel", &textproto.Error{Code: 421, Msg: "closing connection"}, true},
+		{"mailbox busy", &textproto.Error{Code: 450, Msg: "mailbox busy"}, false},
+		{"no such user", &textproto.Error{Code: 550, Msg: "no such user"}, false},
+		{"other", errors.New("invalid address"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionError(tt.err); got != tt.want {
+				t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,33 @@ import (
 	"github.com/google/uuid"
 )
 
+type Status string
+
+const (
+	StatusConfirmed Status = "confirmed"
+	StatusWaitlist  Status = "waitlist"
+)
+
 type Enrollment struct {
 	Id        uuid.UUID
 	CreatedAt time.Time
-	UserEmail string
+	UserID    uuid.UUID
 	EventId   uuid.UUID
+	Status    Status
+	// ReminderOffsets overrides the reminder offsets of the user for this
+	// event, nil means no override.
+	ReminderOffsets []int
+}
+
+// Attendee is an enrollment together with the public data of the enrolled
+// user, as shown to the organizer of the event.
+type Attendee struct {
+	EnrollmentID uuid.UUID `json:"enrollment_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Name         string    `json:"name"`
+	LastName     string    `json:"last_name"`
+	Email        string    `json:"email"`
+	Status       Status    `json:"status"`
+	Position     int       `json:"position,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
 }
@@ -0,0 +1,101 @@
+package event
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fields of the event that users see. They name the changes in FieldChange.
+const (
+	FieldTitle          = "title"
+	FieldType           = "type"
+	FieldDate           = "date"
+	FieldEndsAt         = "ends_at"
+	FieldTimeZone       = "time_zone"
+	FieldLocation       = "location"
+	FieldDescription    = "description"
+	FieldTotalSeats     = "total_seats"
+	FieldUnlimitedSeats = "has_unlimited_seats"
+	FieldTags           = "tags"
+)
+
+// FieldChange is one changed field. Old and New hold values of the field's
+// own type: string, int, time.Time, orb.Point, bool or []string.
+type FieldChange struct {
+	Field string
+	Old   any
+	New   any
+}
+
+// Diff lists the user-visible fields that differ between the two versions of
+// the event, in a fixed order. Available seats are left out, they change with
+// every enrollment.
+func Diff(old, new Event) []FieldChange {
+	var changes []FieldChange
+
+	if old.Title != new.Title {
+		changes = append(changes, FieldChange{FieldTitle, old.Title, new.Title})
+	}
+
+	if old.Type != new.Type {
+		changes = append(changes, FieldChange{FieldType, old.Type, new.Type})
+	}
+
+	if !old.Date.Equal(new.Date) {
+		changes = append(changes, FieldChange{FieldDate, old.Date, new.Date})
+	}
+
+	if !old.EndsAt.Equal(new.EndsAt) {
+		changes = append(changes, FieldChange{FieldEndsAt, old.EndsAt, new.EndsAt})
+	}
+
+	if old.TimeZone != new.TimeZone {
+		changes = append(changes, FieldChange{FieldTimeZone, old.TimeZone, new.TimeZone})
+	}
+
+	if !old.Location.Equal(new.Location) {
+		changes = append(changes, FieldChange{FieldLocation, old.Location, new.Location})
+	}
+
+	if description(old) != description(new) {
+		changes = append(changes, FieldChange{FieldDescription, description(old), description(new)})
+	}
+
+	if old.TotalSeats != new.TotalSeats {
+		changes = append(changes, FieldChange{FieldTotalSeats, old.TotalSeats, new.TotalSeats})
+	}
+
+	if old.IsUnlimited() != new.IsUnlimited() {
+		changes = append(changes, FieldChange{FieldUnlimitedSeats, old.IsUnlimited(), new.IsUnlimited()})
+	}
+
+	if !slices.Equal(old.Tags, new.Tags) {
+		changes = append(changes, FieldChange{FieldTags, old.Tags, new.Tags})
+	}
+
+	return changes
+}
+
+// IsUnlimited reports whether seats are not limited. HasUnlimitedSeats is a
+// string for historical reasons and is compared without regard to case.
+func (e Event) IsUnlimited() bool {
+	return strings.EqualFold(e.HasUnlimitedSeats, "true")
+}
+
+func description(e Event) string {
+	if e.Description == nil {
+		return ""
+	}
+	return *e.Description
+}
+
+// PendingChange collects the edits of an event that users have not been told
+// about yet. Old is the event as it was before the first of them.
+type PendingChange struct {
+	EventID   uuid.UUID
+	Old       Event
+	CreatedAt time.Time
+}
@@ -0,0 +1,149 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+)
+
+func TestDiff(t *testing.T) {
+	date := time.Date(2025, time.June, 1, 18, 0, 0, 0, time.UTC)
+	text := "Bring a laptop"
+	other := "Bring nothing"
+	empty := ""
+
+	base := Event{
+		Title:             "Go meetup",
+		Type:              1,
+		Date:              date,
+		EndsAt:            date.Add(2 * time.Hour),
+		TimeZone:          "Europe/Moscow",
+		TotalSeats:        30,
+		AvailableSeats:    12,
+		Location:          orb.Point{49.12, 55.79},
+		HasUnlimitedSeats: "false",
+		Description:       &text,
+		Tags:              []string{"go", "meetup"},
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Event)
+		want   []FieldChange
+	}{
+		{
+			name:   "nothing",
+			change: func(*Event) {},
+		},
+		{
+			name:   "available seats",
+			change: func(e *Event) { e.AvailableSeats = 3 },
+		},
+		{
+			name:   "same instant in another zone",
+			change: func(e *Event) { e.Date = e.Date.In(time.FixedZone("MSK", 3*60*60)) },
+		},
+		{
+			name:   "description cleared",
+			change: func(e *Event) { e.Description = &empty },
+			want:   []FieldChange{{FieldDescription, text, ""}},
+		},
+		{
+			name:   "unlimited seats case",
+			change: func(e *Event) { e.HasUnlimitedSeats = "FALSE" },
+		},
+		{
+			name:   "title",
+			change: func(e *Event) { e.Title = "Go conference" },
+			want:   []FieldChange{{FieldTitle, "Go meetup", "Go conference"}},
+		},
+		{
+			name:   "type",
+			change: func(e *Event) { e.Type = 2 },
+			want:   []FieldChange{{FieldType, 1, 2}},
+		},
+		{
+			name:   "description",
+			change: func(e *Event) { e.Description = &other },
+			want:   []FieldChange{{FieldDescription, text, other}},
+		},
+		{
+			name:   "location",
+			change: func(e *Event) { e.Location = orb.Point{37.62, 55.75} },
+			want:   []FieldChange{{FieldLocation, orb.Point{49.12, 55.79}, orb.Point{37.62, 55.75}}},
+		},
+		{
+			name:   "unlimited seats",
+			change: func(e *Event) { e.HasUnlimitedSeats = "true" },
+			want:   []FieldChange{{FieldUnlimitedSeats, false, true}},
+		},
+		{
+			name:   "tags",
+			change: func(e *Event) { e.Tags = []string{"go"} },
+			want:   []FieldChange{{FieldTags, []string{"go", "meetup"}, []string{"go"}}},
+		},
+		{
+			name: "several fields in order",
+			change: func(e *Event) {
+				e.TotalSeats = 40
+				e.TimeZone = "UTC"
+				e.Date = e.Date.Add(time.Hour)
+				e.EndsAt = e.EndsAt.Add(time.Hour)
+			},
+			want: []FieldChange{
+				{FieldDate, date, date.Add(time.Hour)},
+				{FieldEndsAt, date.Add(2 * time.Hour), date.Add(3 * time.Hour)},
+				{FieldTimeZone, "Europe/Moscow", "UTC"},
+				{FieldTotalSeats, 30, 40},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			changed.Tags = append([]string(nil), base.Tags...)
+			tt.change(&changed)
+
+			got := Diff(base, changed)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffRevert follows a batch of edits the way the change queue does: the
+// event is compared with the version before the first edit, so an edit that
+// is undone before the letters go out leaves nothing to tell.
+func TestDiffRevert(t *testing.T) {
+	date := time.Date(2025, time.June, 1, 18, 0, 0, 0, time.UTC)
+
+	old := Event{Title: "Go meetup", Date: date, TotalSeats: 30, Tags: []string{"go"}}
+
+	moved := old
+	moved.Date = date.Add(24 * time.Hour)
+	moved.Title = "Go meetup (moved)"
+
+	if got := Diff(old, moved); len(got) != 2 {
+		t.Fatalf("Diff() after the change = %v, want the title and the date", got)
+	}
+
+	reverted := moved
+	reverted.Date = date
+	reverted.Title = "Go meetup"
+
+	if got := Diff(old, reverted); got != nil {
+		t.Errorf("Diff() after the revert = %v, want no changes", got)
+	}
+
+	partly := moved
+	partly.Title = "Go meetup"
+
+	want := []FieldChange{{FieldDate, date, date.Add(24 * time.Hour)}}
+	if got := Diff(old, partly); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() after a partial revert = %v, want %v", got, want)
+	}
+}
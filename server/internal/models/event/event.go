@@ -1,21 +1,90 @@
 package event
 
 import (
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/paulmach/orb"
 )
 
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusPublished Status = "published"
+	StatusCancelled Status = "cancelled"
+	// StatusCompleted is reported for published events that have started.
+	StatusCompleted Status = "completed"
+)
+
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusDraft, StatusPublished, StatusCancelled, StatusCompleted:
+		return true
+	}
+	return false
+}
+
 type Event struct {
-	ID                uuid.UUID `json:"id"`
-	Title             string    `json:"title"`
-	Type              int       `json:"type"`
-	Date              time.Time `json:"date"`
-	TotalSeats        int       `json:"total_seats"`
-	AvailableSeats    int       `json:"available_seats"`
-	CreatorEmail      string    `json:"creator_email"`
-	Location          orb.Point `json:"location"`
-	HasUnlimitedSeats string    `json:"has_unlimited_seats"`
-	Description       *string   `json:"description,omitempty"`
+	ID                 uuid.UUID `json:"id"`
+	Title              string    `json:"title"`
+	Type               int       `json:"type"`
+	Date               time.Time `json:"date"`
+	EndsAt             time.Time `json:"ends_at"`
+	TimeZone           string    `json:"time_zone"`
+	TotalSeats         int       `json:"total_seats"`
+	AvailableSeats     int       `json:"available_seats"`
+	CreatorID          uuid.UUID `json:"creator_id"`
+	Location           orb.Point `json:"location"`
+	HasUnlimitedSeats  string    `json:"has_unlimited_seats"`
+	Description        *string   `json:"description,omitempty"`
+	Status             Status    `json:"status"`
+	CancellationReason *string   `json:"cancellation_reason,omitempty"`
+	// PublishAt is when a draft gets published, or when a published event
+	// was.
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// Occurrences of a recurring event belong to a series. RecurrenceID is
+	// the start the rule gave the occurrence, before it was moved.
+	SeriesID     *uuid.UUID `json:"series_id,omitempty"`
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty"`
+	RRule        *string    `json:"rrule,omitempty"`
+	// Tags are free-form labels, normalized by NormalizeTags.
+	Tags []string `json:"tags"`
+	// Snippet is the text matching the search query, with the matches
+	// marked. It is only set on search results.
+	Snippet *string `json:"snippet,omitempty"`
+	// Sequence counts the changes of the event for calendar invites.
+	Sequence int `json:"-"`
+}
+
+// DefaultTimeZone is the zone of events created without one.
+const DefaultTimeZone = "UTC"
+
+var zones sync.Map
+
+// LoadZone returns the IANA zone with the given name, or UTC if there is no
+// such zone. Zones are loaded once.
+func LoadZone(name string) *time.Location {
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		loc = time.UTC
+	}
+
+	zones.Store(name, loc)
+	return loc
+}
+
+// Zone returns the zone the event is held in.
+func (e Event) Zone() *time.Location {
+	return LoadZone(e.TimeZone)
+}
+
+// Duration is how long the event lasts.
+func (e Event) Duration() time.Duration {
+	return e.EndsAt.Sub(e.Date)
 }
@@ -0,0 +1,121 @@
+package event
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+)
+
+// Sort is the order events are listed in.
+type Sort string
+
+const (
+	SortDate Sort = "date"
+	// SortDistance orders by the distance from the filter location.
+	SortDistance Sort = "distance"
+	// SortSeats orders by the seats left, events without a limit last.
+	SortSeats     Sort = "seats"
+	SortCreatedAt Sort = "created_at"
+	// SortRelevance orders by how well the event matches the search query.
+	SortRelevance Sort = "relevance"
+)
+
+func (s Sort) IsValid() bool {
+	switch s {
+	case SortDate, SortDistance, SortSeats, SortCreatedAt, SortRelevance:
+		return true
+	}
+	return false
+}
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Filter selects the events to list. Nil fields do not filter.
+type Filter struct {
+	// Types lists the types to include, any of them matches.
+	Types     []int
+	CreatorID *uuid.UUID
+	VisitorID *uuid.UUID
+	// VisitorStatus only counts the enrollments of the visitor in that
+	// status.
+	VisitorStatus *enrollment.Status
+	Status        *Status
+	// Tags lists the tags the events must all have.
+	Tags []string
+	// Query is searched for in the titles and descriptions.
+	Query *string
+	// Before and After bound the start of the event.
+	Before *time.Time
+	After  *time.Time
+	// From and To select the events overlapping the window. Both are the
+	// same for the events happening at that moment.
+	From *time.Time
+	To   *time.Time
+	// Location and Radius, in meters, select the events nearby. Location
+	// alone only serves to sort by distance.
+	Location *orb.Point
+	Radius   *float64
+
+	Sort Sort
+	Desc bool
+	// Limit is the size of the page, 0 lists every event.
+	Limit  int
+	Cursor *Cursor
+	// WithTotal counts the events matching the filter across all pages.
+	WithTotal bool
+}
+
+// Page is a page of the listed events. NextCursor is nil on the last page.
+type Page struct {
+	Events     []Event `json:"events"`
+	NextCursor *Cursor `json:"next_cursor"`
+	Total      *int    `json:"total,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position after the last event of a page: its sort key and
+// id. It is handed to clients as an opaque string.
+type Cursor cursor
+
+// cursor is Cursor without its text encoding, so it can be marshalled.
+type cursor struct {
+	Sort Sort      `json:"s"`
+	Desc bool      `json:"d,omitempty"`
+	Key  string    `json:"k"`
+	ID   uuid.UUID `json:"i"`
+}
+
+func (c Cursor) MarshalText() ([]byte, error) {
+	raw, err := json.Marshal(cursor(c))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, base64.RawURLEncoding.EncodedLen(len(raw)))
+	base64.RawURLEncoding.Encode(out, raw)
+	return out, nil
+}
+
+func (c *Cursor) UnmarshalText(text []byte) error {
+	raw, err := base64.RawURLEncoding.DecodeString(string(text))
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	var dec cursor
+	if err = json.Unmarshal(raw, &dec); err != nil || !dec.Sort.IsValid() || dec.ID == uuid.Nil {
+		return ErrInvalidCursor
+	}
+
+	*c = Cursor(dec)
+	return nil
+}
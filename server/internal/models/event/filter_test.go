@@ -0,0 +1,82 @@
+package event
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("0b7f6c3e-5f4a-4a8e-9d2c-1f0e3b6a7c85")
+
+	tests := []Cursor{
+		{Sort: SortDate, Key: "2025-06-01 18:00:00+00", ID: id},
+		{Sort: SortDate, Desc: true, Key: "2025-06-01 18:00:00.123456+03", ID: id},
+		{Sort: SortDistance, Key: "1234.5678901234", ID: id},
+		{Sort: SortSeats, Desc: true, Key: "2147483647", ID: id},
+		{Sort: SortCreatedAt, Key: "2025-06-01 18:00:00", ID: id},
+		{Sort: SortRelevance, Desc: true, Key: "0.0607927", ID: id},
+		{Sort: SortDate, Key: `"quoted" & <odd>/+=`, ID: id},
+	}
+
+	for _, want := range tests {
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%+v): %v", want, err)
+		}
+
+		for _, b := range text {
+			if b == '+' || b == '/' || b == '=' {
+				t.Errorf("cursor %s is not safe in a URL", text)
+				break
+			}
+		}
+
+		var got Cursor
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%s): %v", text, err)
+		}
+
+		if got != want {
+			t.Errorf("round trip of %+v gave %+v", want, got)
+		}
+	}
+}
+
+func TestCursorUnmarshalMalformed(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padded", base64.URLEncoding.EncodeToString([]byte(`{"s":"date","k":"xy","i":"0b7f6c3e-5f4a-4a8e-9d2c-1f0e3b6a7c85"}`))},
+		{"not json", encode("date|x|0b7f6c3e")},
+		{"truncated json", encode(`{"s":"date","k":"x"`)},
+		{"unknown sort", encode(`{"s":"title","k":"x","i":"0b7f6c3e-5f4a-4a8e-9d2c-1f0e3b6a7c85"}`)},
+		{"no sort", encode(`{"k":"x","i":"0b7f6c3e-5f4a-4a8e-9d2c-1f0e3b6a7c85"}`)},
+		{"no id", encode(`{"s":"date","k":"x"}`)},
+		{"nil id", encode(`{"s":"date","k":"x","i":"00000000-0000-0000-0000-000000000000"}`)},
+		{"bad id", encode(`{"s":"date","k":"x","i":"42"}`)},
+		{"wrong types", encode(`{"s":1,"d":"yes","k":2,"i":"0b7f6c3e-5f4a-4a8e-9d2c-1f0e3b6a7c85"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cursor{Sort: SortSeats, Key: "unchanged"}
+			err := c.UnmarshalText([]byte(tt.text))
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("UnmarshalText(%q) error = %v, want ErrInvalidCursor", tt.text, err)
+			}
+			if c.Sort != SortSeats || c.Key != "unchanged" {
+				t.Errorf("UnmarshalText(%q) changed the cursor to %+v", tt.text, c)
+			}
+		})
+	}
+}
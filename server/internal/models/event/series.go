@@ -0,0 +1,26 @@
+package event
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Series is a recurring event. Its occurrences are events created from the
+// template, one for every start of the rule up to GeneratedUntil.
+type Series struct {
+	ID             uuid.UUID
+	RRule          string
+	Start          time.Time
+	ExDates        []time.Time
+	Template       Event
+	GeneratedUntil time.Time
+	// Finished series have no starts left to generate.
+	Finished bool
+}
+
+// Revision is an occurrence before and after an edit of the series.
+type Revision struct {
+	Old Event
+	New Event
+}
@@ -0,0 +1,28 @@
+package event
+
+import (
+	"slices"
+	"strings"
+)
+
+// Type is a kind of event, managed by admins. Names holds the name of the
+// type in every locale.
+type Type struct {
+	ID    int               `json:"id"`
+	Names map[string]string `json:"names"`
+}
+
+// NormalizeTags trims and lowercases the tags and sorts them, dropping empty
+// and repeated ones, so the same tag is never stored twice.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
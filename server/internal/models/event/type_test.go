@@ -0,0 +1,54 @@
+package event
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	long := strings.Repeat("я", 32)
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{name: "nil", tags: nil, want: []string{}},
+		{name: "case", tags: []string{"Go", "MEETUP", "Встреча"}, want: []string{"go", "meetup", "встреча"}},
+		{name: "whitespace", tags: []string{"  go  ", "open\tsource", "big \n  data"}, want: []string{"big data", "go", "open source"}},
+		{name: "empty", tags: []string{"", "   ", "\t\n", "go"}, want: []string{"go"}},
+		{name: "duplicates", tags: []string{"go", "Go", " GO ", "rust", "go"}, want: []string{"go", "rust"}},
+		{name: "duplicates after normalizing", tags: []string{"Open  Source", "open source", "OPEN\tSOURCE"}, want: []string{"open source"}},
+		{name: "sorted", tags: []string{"c", "a", "b"}, want: []string{"a", "b", "c"}},
+		{name: "longest tag kept whole", tags: []string{long}, want: []string{long}},
+		{name: "long tag collapsed", tags: []string{"  " + strings.Repeat("a ", 20) + "  "}, want: []string{strings.TrimSpace(strings.Repeat("a ", 20))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeTags(tt.tags)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("NormalizeTags(%q) = %q, want %q", tt.tags, got, tt.want)
+			}
+
+			// requests are checked against the limits before normalizing,
+			// which must not make them any longer
+			if len(got) > len(tt.tags) {
+				t.Errorf("NormalizeTags(%q) returned more tags than it was given", tt.tags)
+			}
+			for _, tag := range got {
+				if !slices.ContainsFunc(tt.tags, func(in string) bool {
+					return utf8.RuneCountInString(tag) <= utf8.RuneCountInString(in)
+				}) {
+					t.Errorf("NormalizeTags(%q) made %q longer", tt.tags, tag)
+				}
+			}
+
+			if again := NormalizeTags(got); !slices.Equal(again, got) {
+				t.Errorf("NormalizeTags is not idempotent: %q became %q", got, again)
+			}
+		})
+	}
+}
@@ -1,9 +1,197 @@
 package letter
 
 import (
+	"bytes"
+	"embed"
 	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/google/uuid"
 )
 
+// Message is an email ready to be handed to a mailer.
+type Message struct {
+	To string
+	Content
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message. It is stored with the queued
+// message, so it is encoded as JSON.
+type Attachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Content is a rendered letter. Text is the plain-text alternative of HTML.
+type Content struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Outbound is a queued message that is being delivered.
+type Outbound struct {
+	ID       uuid.UUID
+	Message  Message
+	Attempts int
+}
+
+// DeadLetter is a message that could not be delivered after all attempts.
+type DeadLetter struct {
+	ID        uuid.UUID `json:"id"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+type Locale string
+
+const (
+	LocaleEN Locale = "en"
+	LocaleRU Locale = "ru"
+
+	DefaultLocale = LocaleEN
+)
+
+func (l Locale) IsValid() bool {
+	switch l {
+	case LocaleEN, LocaleRU:
+		return true
+	}
+	return false
+}
+
+// ParseLocale returns the locale with the given code, or DefaultLocale if
+// there are no letters in that language.
+func ParseLocale(code string) Locale {
+	l := Locale(strings.ToLower(code))
+	if !l.IsValid() {
+		return DefaultLocale
+	}
+	return l
+}
+
+// Dates name their zone, since the reader may be in another one.
+var dateFormats = map[Locale]string{
+	LocaleEN: "January 2, 2006 15:04 MST",
+	LocaleRU: "02.01.2006 15:04 MST",
+}
+
+// FormatDate formats the time the way letters in the locale do, in the zone
+// of the time.
+func FormatDate(locale Locale, t time.Time) string {
+	return t.Format(dateFormats[ParseLocale(string(locale))])
+}
+
+// FormatBool writes yes or no in the language of the locale.
+func FormatBool(locale Locale, b bool) string {
+	return boolNames[ParseLocale(string(locale))][b]
+}
+
+var boolNames = map[Locale]map[bool]string{
+	LocaleEN: {true: "yes", false: "no"},
+	LocaleRU: {true: "да", false: "нет"},
+}
+
+// FormatDuration writes the duration in days, hours and minutes in the
+// language of the locale, such as "1 hour 30 minutes". Seconds are dropped.
+func FormatDuration(locale Locale, d time.Duration) string {
+	locale = ParseLocale(string(locale))
+
+	parts := []struct {
+		n    int
+		unit string
+	}{
+		{int(d / (24 * time.Hour)), "day"},
+		{int(d % (24 * time.Hour) / time.Hour), "hour"},
+		{int(d % time.Hour / time.Minute), "minute"},
+	}
+
+	var words []string
+	for _, p := range parts {
+		if p.n > 0 {
+			words = append(words, fmt.Sprintf("%d %s", p.n, unitNames[locale][p.unit][plural(locale, p.n)]))
+		}
+	}
+
+	if len(words) == 0 {
+		return fmt.Sprintf("0 %s", unitNames[locale]["minute"][plural(locale, 0)])
+	}
+
+	return strings.Join(words, " ")
+}
+
+// unitNames holds the plural forms of the units of FormatDuration, indexed
+// by plural. Russian ones are in the accusative, as in "действует 1 минуту".
+var unitNames = map[Locale]map[string][3]string{
+	LocaleEN: {
+		"day":    {"day", "days", "days"},
+		"hour":   {"hour", "hours", "hours"},
+		"minute": {"minute", "minutes", "minutes"},
+	},
+	LocaleRU: {
+		"day":    {"день", "дня", "дней"},
+		"hour":   {"час", "часа", "часов"},
+		"minute": {"минуту", "минуты", "минут"},
+	},
+}
+
+// plural picks the form for n: one, few or many. English only tells one
+// from the rest.
+func plural(locale Locale, n int) int {
+	if locale != LocaleRU {
+		if n == 1 {
+			return 0
+		}
+		return 2
+	}
+
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	}
+	return 2
+}
+
+// fieldNames translates the fields of event.Diff for update notifications.
+var fieldNames = map[Locale]map[string]string{
+	LocaleEN: {
+		event.FieldTitle:          "Title",
+		event.FieldType:           "Type",
+		event.FieldDate:           "Date",
+		event.FieldEndsAt:         "End",
+		event.FieldTimeZone:       "Time zone",
+		event.FieldLocation:       "Location",
+		event.FieldDescription:    "Description",
+		event.FieldTotalSeats:     "Number of seats",
+		event.FieldUnlimitedSeats: "Unlimited seats",
+		event.FieldTags:           "Tags",
+	},
+	LocaleRU: {
+		event.FieldTitle:          "Название",
+		event.FieldType:           "Тип",
+		event.FieldDate:           "Дата",
+		event.FieldEndsAt:         "Окончание",
+		event.FieldTimeZone:       "Часовой пояс",
+		event.FieldLocation:       "Место",
+		event.FieldDescription:    "Описание",
+		event.FieldTotalSeats:     "Количество мест",
+		event.FieldUnlimitedSeats: "Без ограничения мест",
+		event.FieldTags:           "Теги",
+	},
+}
+
 type Change struct {
 	Name       string
 	OldContent string
@@ -18,45 +206,174 @@ func NewChange(name, old, new string) Change {
 	}
 }
 
-func NewUpdateNotification(name, title string, changes []Change) string {
-	text := fmt.Sprintf(`
-	<div>
-		<h1> Hello, dear %s </h1>
-		<p> Details of the event %s have been changed </p>
-		<ul>
-	`,
-		name,
-		title,
-	)
-
-	for _, change := range changes {
-		text += fmt.Sprintf("\n\t\t<li>%s changed from:<br/>%s<br/>to:<br/>%s<br/></li>",
-			change.Name,
-			change.OldContent,
-			change.NewContent,
-		)
-	}
-
-	text += "\n\t</ul>\n</div>"
-
-	return text
-}
-
-func NewReminderNotification(name, title, location, time string) string {
-	text := fmt.Sprintf(`
-		<div> 
-			<h1> Hello, dear %s </h1>
-			<p> We remind you of an upcoming event </p>
-			<h1> %s </h1>
-			<h3> The event will take place at <b> %s </b></h3>
-			<h3> The time of the event is <b> %s </b></h3>
-		</div>
-		`,
-		name,
-		title,
-		location,
-		time,
-	)
-
-	return text
+//go:embed templates
+var templatesFS embed.FS
+
+const (
+	letterUpdate        = "update"
+	letterReminder      = "reminder"
+	letterVerification  = "verification"
+	letterPasswordReset = "password_reset"
+	letterEmailChange   = "email_change"
+	letterPromotion     = "promotion"
+	letterEnrollment    = "enrollment"
+	letterCancellation  = "cancellation"
+	// Letters about recurring events cover every occurrence at once.
+	letterSeriesEnrollment = "series_enrollment"
+)
+
+type templateSet struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// templates holds every letter in every locale. They are parsed once at
+// startup, so a broken template stops the app instead of a delivery.
+var templates = parseTemplates()
+
+func parseTemplates() map[Locale]map[string]templateSet {
+	letters := []string{
+		letterUpdate,
+		letterReminder,
+		letterVerification,
+		letterPasswordReset,
+		letterEmailChange,
+		letterPromotion,
+		letterEnrollment,
+		letterCancellation,
+		letterSeriesEnrollment,
+	}
+
+	sets := make(map[Locale]map[string]templateSet)
+
+	for _, locale := range []Locale{LocaleEN, LocaleRU} {
+		funcs := map[string]any{
+			"date": func(t time.Time) string {
+				return FormatDate(locale, t)
+			},
+			"field": func(name string) string {
+				if translated, ok := fieldNames[locale][name]; ok {
+					return translated
+				}
+				return name
+			},
+		}
+
+		sets[locale] = make(map[string]templateSet, len(letters))
+
+		for _, name := range letters {
+			path := fmt.Sprintf("templates/%s/%s", locale, name)
+
+			sets[locale][name] = templateSet{
+				html: htmltemplate.Must(htmltemplate.New(name+".html").Funcs(funcs).ParseFS(templatesFS, path+".html")),
+				text: texttemplate.Must(texttemplate.New(name+".txt").Funcs(funcs).ParseFS(templatesFS, path+".txt")),
+			}
+		}
+	}
+
+	return sets
+}
+
+// render executes the letter in the given locale. The subject is defined in
+// the plain-text template.
+func render(locale Locale, name string, data any) (Content, error) {
+	set, ok := templates[ParseLocale(string(locale))][name]
+	if !ok {
+		return Content{}, fmt.Errorf("letter %s does not exist", name)
+	}
+
+	var subject, text, html bytes.Buffer
+
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Content{}, fmt.Errorf("error rendering subject of %s: %v", name, err)
+	}
+
+	if err := set.text.Execute(&text, data); err != nil {
+		return Content{}, fmt.Errorf("error rendering text of %s: %v", name, err)
+	}
+
+	if err := set.html.Execute(&html, data); err != nil {
+		return Content{}, fmt.Errorf("error rendering html of %s: %v", name, err)
+	}
+
+	return Content{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
+
+func NewUpdateNotification(locale Locale, name, title string, changes []Change) (Content, error) {
+	return render(locale, letterUpdate, struct {
+		Name    string
+		Title   string
+		Changes []Change
+	}{name, title, changes})
+}
+
+func NewReminderNotification(locale Locale, name, title, location string, date time.Time) (Content, error) {
+	return render(locale, letterReminder, struct {
+		Name     string
+		Title    string
+		Location string
+		Date     time.Time
+	}{name, title, location, date})
+}
+
+func NewVerificationLetter(locale Locale, name, link string) (Content, error) {
+	return render(locale, letterVerification, struct {
+		Name string
+		Link string
+	}{name, link})
+}
+
+func NewPasswordResetLetter(locale Locale, name, link, token string, validFor time.Duration) (Content, error) {
+	return render(locale, letterPasswordReset, struct {
+		Name     string
+		Link     string
+		Token    string
+		ValidFor string
+	}{name, link, token, FormatDuration(locale, validFor)})
+}
+
+func NewEmailChangeLetter(locale Locale, name, link string) (Content, error) {
+	return render(locale, letterEmailChange, struct {
+		Name string
+		Link string
+	}{name, link})
+}
+
+func NewPromotionNotification(locale Locale, name, title string, date time.Time) (Content, error) {
+	return render(locale, letterPromotion, struct {
+		Name  string
+		Title string
+		Date  time.Time
+	}{name, title, date})
+}
+
+func NewEnrollmentConfirmation(locale Locale, name, title, location string, date time.Time) (Content, error) {
+	return render(locale, letterEnrollment, struct {
+		Name     string
+		Title    string
+		Location string
+		Date     time.Time
+	}{name, title, location, date})
+}
+
+func NewCancellationNotification(locale Locale, name, title, reason string, date time.Time) (Content, error) {
+	return render(locale, letterCancellation, struct {
+		Name   string
+		Title  string
+		Reason string
+		Date   time.Time
+	}{name, title, reason, date})
+}
+
+func NewSeriesEnrollmentConfirmation(locale Locale, name, title, location string, first time.Time) (Content, error) {
+	return render(locale, letterSeriesEnrollment, struct {
+		Name     string
+		Title    string
+		Location string
+		First    time.Time
+	}{name, title, location, first})
 }
@@ -0,0 +1,33 @@
+package letter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		locale Locale
+		d      time.Duration
+		want   string
+	}{
+		{LocaleEN, time.Hour, "1 hour"},
+		{LocaleEN, 90 * time.Minute, "1 hour 30 minutes"},
+		{LocaleEN, 48*time.Hour + time.Minute, "2 days 1 minute"},
+		{LocaleEN, 30 * time.Second, "0 minutes"},
+		{LocaleRU, time.Hour, "1 час"},
+		{LocaleRU, 2 * time.Hour, "2 часа"},
+		{LocaleRU, 5 * time.Hour, "5 часов"},
+		{LocaleRU, 21 * time.Minute, "21 минуту"},
+		{LocaleRU, 12 * time.Minute, "12 минут"},
+		{LocaleRU, 24 * time.Minute, "24 минуты"},
+		{LocaleRU, 11 * 24 * time.Hour, "11 дней"},
+		{"de", time.Hour, "1 hour"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatDuration(tt.locale, tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%s, %v) = %q, want %q", tt.locale, tt.d, got, tt.want)
+		}
+	}
+}
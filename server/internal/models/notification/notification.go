@@ -0,0 +1,18 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scheduled is a reminder about an event for one enrollment, due at SendAt.
+type Scheduled struct {
+	ID            uuid.UUID
+	EnrollmentID  uuid.UUID
+	UserID        uuid.UUID
+	EventID       uuid.UUID
+	OffsetMinutes int
+	SendAt        time.Time
+	Attempts      int
+}
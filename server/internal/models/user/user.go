@@ -1,15 +1,72 @@
 package user
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	// RoleUser can only enroll in events
+	RoleUser Role = "user"
+	// RoleOrganizer can also create events and manage their own events
+	RoleOrganizer Role = "organizer"
+	// RoleAdmin can manage every event and every user
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleUser, RoleOrganizer, RoleAdmin:
+		return true
+	}
+	return false
+}
 
 type User struct {
-	Name     string `json:"name"`
-	LastName string `json:"last_name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	LastName   string     `json:"last_name"`
+	Email      string     `json:"email"`
+	Password   string     `json:"password"`
+	Role       Role       `json:"role"`
+	Locale     string     `json:"locale"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	// TimeZone is the IANA zone letters are written in. Without one the
+	// zone of the event is used.
+	TimeZone *string `json:"time_zone"`
+}
+
+func (u User) IsVerified() bool {
+	return u.VerifiedAt != nil
 }
 
+// ResetRequest is a queued request to mail a password reset token to the
+// address.
+type ResetRequest struct {
+	ID    uuid.UUID
+	Email string
+}
+
+// NotificationSettings holds the minutes before an event at which the user
+// is reminded of it.
+type NotificationSettings struct {
+	ReminderOffsets []int `json:"reminder_offsets"`
+}
+
+// UserClaims are the claims of an access token: the subject is the id of the
+// user and the token id is the id of the session. Everything else about the
+// user is read from storage.
 type UserClaims struct {
-	Payload User `json:"payload"`
 	jwt.RegisteredClaims
 }
+
+type Tokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
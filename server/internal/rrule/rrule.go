@@ -0,0 +1,494 @@
+// Package rrule parses the recurrence rules of RFC 5545 and expands them into
+// the starts of the occurrences. Only the parts events need are supported:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY, BYMONTH and WKST. Rules with any other part are rejected rather
+// than expanded wrong.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalid = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxYears bounds the search for occurrences, so a rule that never matches,
+// like the 30th of February, cannot loop forever.
+const maxYears = 100
+
+// Weekday is a BYDAY value. N is the ordinal within the month, 1 for the
+// first and -1 for the last, or 0 for every such day.
+type Weekday struct {
+	N   int
+	Day time.Weekday
+}
+
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// Count and Until are zero when the rule is unbounded. At most one of
+	// them is set.
+	Count      int
+	Until      time.Time
+	ByDay      []Weekday
+	ByMonthDay []int
+	ByMonth    []int
+	WeekStart  time.Weekday
+}
+
+var dayNames = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var dayCodes = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU",
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10". The
+// "RRULE:" prefix is optional.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1, WeekStart: time.Monday}
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("%w: empty rule", ErrInvalid)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalid, part)
+		}
+
+		name = strings.ToUpper(name)
+		value = strings.ToUpper(value)
+
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s is given twice", ErrInvalid, name)
+		}
+		seen[name] = true
+
+		var err error
+
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(value)
+			switch r.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = parseInt(value, 1, 1000)
+		case "COUNT":
+			r.Count, err = parseInt(value, 1, 10000)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseList(value, -31, 31)
+		case "BYMONTH":
+			r.ByMonth, err = parseList(value, 1, 12)
+		case "WKST":
+			day, ok := dayNames[value]
+			if !ok {
+				err = fmt.Errorf("unknown weekday %s", value)
+			}
+			r.WeekStart = day
+		default:
+			err = fmt.Errorf("%s is not supported", name)
+		}
+
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+
+	if r.Freq == "" {
+		return Rule{}, fmt.Errorf("%w: FREQ is required", ErrInvalid)
+	}
+
+	if r.Count > 0 && !r.Until.IsZero() {
+		return Rule{}, fmt.Errorf("%w: COUNT and UNTIL cannot be used together", ErrInvalid)
+	}
+
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly && !(r.Freq == Yearly && len(r.ByMonth) > 0) {
+			return Rule{}, fmt.Errorf("%w: numbered BYDAY is only supported for monthly rules", ErrInvalid)
+		}
+	}
+
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return Rule{}, fmt.Errorf("%w: BYMONTHDAY cannot be used with weekly rules", ErrInvalid)
+	}
+
+	if len(r.ByDay) > 0 && r.Freq == Yearly && len(r.ByMonth) == 0 {
+		return Rule{}, fmt.Errorf("%w: BYDAY needs BYMONTH in yearly rules", ErrInvalid)
+	}
+
+	return r, nil
+}
+
+func parseInt(value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s is not a number between %d and %d", value, min, max)
+	}
+	return n, nil
+}
+
+func parseList(value string, min, max int) ([]int, error) {
+	var list []int
+
+	for _, item := range strings.Split(value, ",") {
+		n, err := parseInt(item, min, max)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("0 is not allowed")
+		}
+		list = append(list, n)
+	}
+
+	return list, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// a date includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %s is not a date", value)
+}
+
+func parseByDay(value string) ([]Weekday, error) {
+	var days []Weekday
+
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("unknown weekday %s", item)
+		}
+
+		code := item[len(item)-2:]
+		day, ok := dayNames[code]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %s", item)
+		}
+
+		var n int
+		if ordinal := item[:len(item)-2]; ordinal != "" {
+			var err error
+			n, err = parseInt(strings.TrimPrefix(ordinal, "+"), -5, 5)
+			if err != nil || n == 0 {
+				return nil, fmt.Errorf("bad ordinal in %s", item)
+			}
+		}
+
+		days = append(days, Weekday{N: n, Day: day})
+	}
+
+	return days, nil
+}
+
+// String writes the rule back in the form Parse reads.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			if day.N != 0 {
+				days = append(days, fmt.Sprintf("%d%s", day.N, dayCodes[day.Day]))
+			} else {
+				days = append(days, dayCodes[day.Day])
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayCodes[r.WeekStart])
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func joinInts(list []int) string {
+	items := make([]string, 0, len(list))
+	for _, n := range list {
+		items = append(items, strconv.Itoa(n))
+	}
+	return strings.Join(items, ",")
+}
+
+// Bounded reports whether the rule ends, with COUNT or UNTIL.
+func (r Rule) Bounded() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// Between returns the starts of the occurrences of the rule beginning at
+// dtstart that fall after after and no later than before, without the
+// exdates. At most limit starts are returned.
+func (r Rule) Between(dtstart time.Time, exdates []time.Time, after, before time.Time, limit int) []time.Time {
+	var starts []time.Time
+
+	r.each(dtstart, func(t time.Time) bool {
+		if t.After(before) || len(starts) >= limit {
+			return false
+		}
+		if t.After(after) && !slices.ContainsFunc(exdates, t.Equal) {
+			starts = append(starts, t)
+		}
+		return true
+	})
+
+	return starts
+}
+
+// After returns the first start of the rule later than t, exdates included.
+// It is false when the rule has ended by then.
+func (r Rule) After(dtstart time.Time, t time.Time) (time.Time, bool) {
+	var next time.Time
+	var ok bool
+
+	r.each(dtstart, func(start time.Time) bool {
+		if start.After(t) {
+			next, ok = start, true
+			return false
+		}
+		return true
+	})
+
+	return next, ok
+}
+
+// CountBefore returns the number of starts of the rule earlier than t,
+// exdates included, as COUNT counts them.
+func (r Rule) CountBefore(dtstart time.Time, t time.Time) int {
+	var n int
+
+	r.each(dtstart, func(start time.Time) bool {
+		if !start.Before(t) {
+			return false
+		}
+		n++
+		return true
+	})
+
+	return n
+}
+
+// each calls fn with every start of the rule in order, until fn returns
+// false or the rule ends. Occurrences keep the time of day of dtstart.
+func (r Rule) each(dtstart time.Time, fn func(time.Time) bool) {
+	var n int
+	limit := dtstart.AddDate(maxYears, 0, 0)
+
+	for period := 0; ; period++ {
+		base, ok := r.period(dtstart, period)
+		if !ok || base.After(limit) {
+			return
+		}
+
+		for _, start := range r.expand(dtstart, base) {
+			if start.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && start.After(r.Until) {
+				return
+			}
+			if r.Count > 0 && n >= r.Count {
+				return
+			}
+			n++
+			if !fn(start) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the first day of the n-th period of the rule.
+func (r Rule) period(dtstart time.Time, n int) (time.Time, bool) {
+	step := n * r.Interval
+	y, m, d := dtstart.Date()
+	loc := dtstart.Location()
+
+	switch r.Freq {
+	case Daily:
+		return time.Date(y, m, d+step, 0, 0, 0, 0, loc), true
+	case Weekly:
+		back := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		return time.Date(y, m, d-back+7*step, 0, 0, 0, 0, loc), true
+	case Monthly:
+		return time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc), true
+	case Yearly:
+		return time.Date(y+step, time.January, 1, 0, 0, 0, 0, loc), true
+	}
+	return time.Time{}, false
+}
+
+// expand returns the starts within the period beginning at base, in order.
+func (r Rule) expand(dtstart time.Time, base time.Time) []time.Time {
+	var days []time.Time
+
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{base}
+	case Weekly:
+		weekdays := r.ByDay
+		if len(weekdays) == 0 {
+			weekdays = []Weekday{{Day: dtstart.Weekday()}}
+		}
+		for i := 0; i < 7; i++ {
+			day := base.AddDate(0, 0, i)
+			if slices.ContainsFunc(weekdays, func(w Weekday) bool { return w.Day == day.Weekday() }) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		days = r.monthDays(dtstart, base)
+	case Yearly:
+		// BYMONTHDAY alone selects the days in every month
+		months := r.ByMonth
+		if len(months) == 0 && len(r.ByMonthDay) == 0 {
+			months = []int{int(dtstart.Month())}
+		}
+		for month := time.January; month <= time.December; month++ {
+			if len(months) == 0 || slices.Contains(months, int(month)) {
+				days = append(days, r.monthDays(dtstart, base.AddDate(0, int(month)-1, 0))...)
+			}
+		}
+	}
+
+	starts := make([]time.Time, 0, len(days))
+	hour, minute, second := dtstart.Clock()
+
+	for _, day := range days {
+		if !r.matches(day) {
+			continue
+		}
+		y, m, d := day.Date()
+		starts = append(starts, time.Date(y, m, d, hour, minute, second, dtstart.Nanosecond(), dtstart.Location()))
+	}
+
+	return starts
+}
+
+// monthDays returns the days of the month beginning at first that the
+// BYMONTHDAY and BYDAY parts select, or the day of dtstart if there are
+// none.
+func (r Rule) monthDays(dtstart time.Time, first time.Time) []time.Time {
+	length := first.AddDate(0, 1, -1).Day()
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if dtstart.Day() > length {
+			return nil
+		}
+		return []time.Time{first.AddDate(0, 0, dtstart.Day()-1)}
+	}
+
+	var days []time.Time
+
+	for d := 1; d <= length; d++ {
+		day := first.AddDate(0, 0, d-1)
+
+		if len(r.ByMonthDay) > 0 && !slices.Contains(r.ByMonthDay, d) && !slices.Contains(r.ByMonthDay, d-length-1) {
+			continue
+		}
+
+		if len(r.ByDay) > 0 && !slices.ContainsFunc(r.ByDay, func(w Weekday) bool {
+			if w.Day != day.Weekday() {
+				return false
+			}
+			switch {
+			case w.N > 0:
+				return (d-1)/7+1 == w.N
+			case w.N < 0:
+				return (length-d)/7+1 == -w.N
+			}
+			return true
+		}) {
+			continue
+		}
+
+		days = append(days, day)
+	}
+
+	return days
+}
+
+// matches applies the parts that only filter the days of the period.
+func (r Rule) matches(day time.Time) bool {
+	if len(r.ByMonth) > 0 && !slices.Contains(r.ByMonth, int(day.Month())) {
+		return false
+	}
+
+	if r.Freq == Daily && len(r.ByDay) > 0 &&
+		!slices.ContainsFunc(r.ByDay, func(w Weekday) bool { return w.Day == day.Weekday() }) {
+		return false
+	}
+
+	if r.Freq == Daily && len(r.ByMonthDay) > 0 {
+		length := day.AddDate(0, 1, -day.Day()).Day()
+		if !slices.Contains(r.ByMonthDay, day.Day()) && !slices.Contains(r.ByMonthDay, day.Day()-length-1) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,393 @@
+package rrule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// newYork is the zone of the examples in RFC 5545, section 3.8.5.3. It
+// switched from daylight saving time on 26 October 1997.
+var newYork = mustLoad("America/New_York")
+
+func mustLoad(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// local parses the times in New York, in the form the RFC writes them.
+func local(t *testing.T, values ...string) []time.Time {
+	t.Helper()
+
+	times := make([]time.Time, 0, len(values))
+	for _, value := range values {
+		parsed, err := time.ParseInLocation("20060102T150405", value, newYork)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", value, err)
+		}
+		times = append(times, parsed)
+	}
+	return times
+}
+
+// The examples of RFC 5545, section 3.8.5.3, the ones of them this package
+// supports.
+func TestBetweenRFCExamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		exdates []string
+		limit   int
+		want    []string
+	}{
+		{
+			name:    "daily for 10 occurrences",
+			rule:    "FREQ=DAILY;COUNT=10",
+			dtstart: "19970902T090000",
+			want: []string{
+				"19970902T090000", "19970903T090000", "19970904T090000", "19970905T090000", "19970906T090000",
+				"19970907T090000", "19970908T090000", "19970909T090000", "19970910T090000", "19970911T090000",
+			},
+		},
+		{
+			name:    "every 10 days, 5 occurrences",
+			rule:    "FREQ=DAILY;INTERVAL=10;COUNT=5",
+			dtstart: "19970902T090000",
+			want: []string{
+				"19970902T090000", "19970912T090000", "19970922T090000", "19971002T090000", "19971012T090000",
+			},
+		},
+		{
+			name:    "every other day, forever",
+			rule:    "FREQ=DAILY;INTERVAL=2",
+			dtstart: "19970902T090000",
+			limit:   4,
+			want: []string{
+				"19970902T090000", "19970904T090000", "19970906T090000", "19970908T090000",
+			},
+		},
+		{
+			name:    "weekly for 10 occurrences",
+			rule:    "FREQ=WEEKLY;COUNT=10",
+			dtstart: "19970902T090000",
+			want: []string{
+				"19970902T090000", "19970909T090000", "19970916T090000", "19970923T090000", "19970930T090000",
+				"19971007T090000", "19971014T090000", "19971021T090000", "19971028T090000", "19971104T090000",
+			},
+		},
+		{
+			name:    "weekly on Tuesday and Thursday for five weeks, with UNTIL",
+			rule:    "FREQ=WEEKLY;UNTIL=19971007T000000Z;WKST=SU;BYDAY=TU,TH",
+			dtstart: "19970902T090000",
+			want: []string{
+				"19970902T090000", "19970904T090000", "19970909T090000", "19970911T090000", "19970916T090000",
+				"19970918T090000", "19970923T090000", "19970925T090000", "19970930T090000", "19971002T090000",
+			},
+		},
+		{
+			name:    "weekly on Tuesday and Thursday for five weeks, with COUNT",
+			rule:    "FREQ=WEEKLY;COUNT=10;WKST=SU;BYDAY=TU,TH",
+			dtstart: "19970902T090000",
+			want: []string{
+				"19970902T090000", "19970904T090000", "19970909T090000", "19970911T090000", "19970916T090000",
+				"19970918T090000", "19970923T090000", "19970925T090000", "19970930T090000", "19971002T090000",
+			},
+		},
+		{
+			name:    "every other week on Monday, Wednesday and Friday until 24 December",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR",
+			dtstart: "19970901T090000",
+			want: []string{
+				"19970901T090000", "19970903T090000", "19970905T090000", "19970915T090000", "19970917T090000",
+				"19970919T090000", "19970929T090000", "19971001T090000", "19971003T090000", "19971013T090000",
+				"19971015T090000", "19971017T090000", "19971027T090000", "19971029T090000", "19971031T090000",
+				"19971110T090000", "19971112T090000", "19971114T090000", "19971124T090000", "19971126T090000",
+				"19971128T090000", "19971208T090000", "19971210T090000", "19971212T090000", "19971222T090000",
+			},
+		},
+		{
+			name:    "monthly on the first Friday for 10 occurrences",
+			rule:    "FREQ=MONTHLY;COUNT=10;BYDAY=1FR",
+			dtstart: "19970905T090000",
+			want: []string{
+				"19970905T090000", "19971003T090000", "19971107T090000", "19971205T090000", "19980102T090000",
+				"19980206T090000", "19980306T090000", "19980403T090000", "19980501T090000", "19980605T090000",
+			},
+		},
+		{
+			name:    "every other month on the first and last Sunday",
+			rule:    "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU",
+			dtstart: "19970907T090000",
+			want: []string{
+				"19970907T090000", "19970928T090000", "19971102T090000", "19971130T090000", "19980104T090000",
+				"19980125T090000", "19980301T090000", "19980329T090000", "19980503T090000", "19980531T090000",
+			},
+		},
+		{
+			name:    "monthly on the second-to-last Monday for 6 months",
+			rule:    "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO",
+			dtstart: "19970922T090000",
+			want: []string{
+				"19970922T090000", "19971020T090000", "19971117T090000", "19971222T090000", "19980119T090000",
+				"19980216T090000",
+			},
+		},
+		{
+			name:    "monthly on the third-to-last day",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-3",
+			dtstart: "19970928T090000",
+			limit:   6,
+			want: []string{
+				"19970928T090000", "19971029T090000", "19971128T090000", "19971229T090000", "19980129T090000",
+				"19980226T090000",
+			},
+		},
+		{
+			name:    "monthly on the 2nd and 15th for 10 occurrences",
+			rule:    "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15",
+			dtstart: "19970902T090000",
+			want: []string{
+				"19970902T090000", "19970915T090000", "19971002T090000", "19971015T090000", "19971102T090000",
+				"19971115T090000", "19971202T090000", "19971215T090000", "19980102T090000", "19980115T090000",
+			},
+		},
+		{
+			name:    "monthly on the first and last day for 10 occurrences",
+			rule:    "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1",
+			dtstart: "19970930T090000",
+			want: []string{
+				"19970930T090000", "19971001T090000", "19971031T090000", "19971101T090000", "19971130T090000",
+				"19971201T090000", "19971231T090000", "19980101T090000", "19980131T090000", "19980201T090000",
+			},
+		},
+		{
+			name:    "yearly in June and July for 10 occurrences",
+			rule:    "FREQ=YEARLY;COUNT=10;BYMONTH=6,7",
+			dtstart: "19970610T090000",
+			want: []string{
+				"19970610T090000", "19970710T090000", "19980610T090000", "19980710T090000", "19990610T090000",
+				"19990710T090000", "20000610T090000", "20000710T090000", "20010610T090000", "20010710T090000",
+			},
+		},
+		{
+			name:    "every Friday the 13th",
+			rule:    "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			dtstart: "19970902T090000",
+			exdates: []string{"19970902T090000"},
+			limit:   5,
+			want: []string{
+				"19980213T090000", "19980313T090000", "19981113T090000", "19990813T090000", "20001013T090000",
+			},
+		},
+		{
+			name:    "the fourth Thursday of November",
+			rule:    "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			dtstart: "19971127T090000",
+			limit:   3,
+			want: []string{
+				"19971127T090000", "19981126T090000", "19991125T090000",
+			},
+		},
+		{
+			name:    "weekly with exdates, which COUNT still counts",
+			rule:    "FREQ=WEEKLY;COUNT=5",
+			dtstart: "19970902T090000",
+			exdates: []string{"19970909T090000", "19970923T090000"},
+			want: []string{
+				"19970902T090000", "19970916T090000", "19970930T090000",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+
+			limit := tt.limit
+			if limit == 0 {
+				limit = 1000
+			}
+
+			dtstart := local(t, tt.dtstart)[0]
+			after := dtstart.Add(-time.Second)
+			before := dtstart.AddDate(10, 0, 0)
+
+			got := r.Between(dtstart, local(t, tt.exdates...), after, before, limit)
+			want := local(t, tt.want...)
+
+			if !slices.EqualFunc(got, want, time.Time.Equal) {
+				t.Errorf("Between() =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
+// TestBetweenDST checks that occurrences keep their wall clock time when the
+// zone switches from or to daylight saving time in between.
+func TestBetweenDST(t *testing.T) {
+	r, err := Parse("FREQ=DAILY;UNTIL=19971224T000000Z")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	dtstart := local(t, "19970902T090000")[0]
+	got := r.Between(dtstart, nil, dtstart.Add(-time.Second), dtstart.AddDate(1, 0, 0), 1000)
+
+	if len(got) != 113 {
+		t.Fatalf("got %d occurrences, want 113 from 2 September to 23 December", len(got))
+	}
+
+	for _, start := range got {
+		if start.Hour() != 9 || start.Minute() != 0 {
+			t.Errorf("occurrence %v is not at 09:00 local time", start)
+		}
+	}
+
+	if utc := got[0].UTC().Hour(); utc != 13 {
+		t.Errorf("first occurrence is at %02d:00 UTC, want 13:00 in daylight saving time", utc)
+	}
+	if utc := got[len(got)-1].UTC().Hour(); utc != 14 {
+		t.Errorf("last occurrence is at %02d:00 UTC, want 14:00 in standard time", utc)
+	}
+
+	r, err = Parse("FREQ=WEEKLY;BYDAY=SU;COUNT=3")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// the clocks go forward on 8 March 2026
+	dtstart = local(t, "20260301T100000")[0]
+	got = r.Between(dtstart, nil, dtstart.Add(-time.Second), dtstart.AddDate(1, 0, 0), 10)
+	want := local(t, "20260301T100000", "20260308T100000", "20260315T100000")
+
+	if !slices.EqualFunc(got, want, time.Time.Equal) {
+		t.Errorf("Between() = %v, want %v", got, want)
+	}
+	if gap := got[2].Sub(got[1]); gap != 7*24*time.Hour {
+		t.Errorf("gap after the switch = %v, want a full week", gap)
+	}
+	if gap := got[1].Sub(got[0]); gap != 7*24*time.Hour-time.Hour {
+		t.Errorf("gap across the switch = %v, want a week less an hour", gap)
+	}
+}
+
+// TestOccurrenceGuards checks that expansion stops: at the limit given, and
+// after maxYears for rules that never or no longer match.
+func TestOccurrenceGuards(t *testing.T) {
+	dtstart := local(t, "19970902T090000")[0]
+	farFuture := dtstart.AddDate(1000, 0, 0)
+
+	daily, err := Parse("FREQ=DAILY")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if got := daily.Between(dtstart, nil, dtstart.Add(-time.Second), farFuture, 50); len(got) != 50 {
+		t.Errorf("Between() returned %d occurrences, want the limit of 50", len(got))
+	}
+
+	if _, ok := daily.After(dtstart, dtstart.AddDate(maxYears+1, 0, 0)); ok {
+		t.Errorf("After() found an occurrence more than %d years after the start", maxYears)
+	}
+
+	never, err := Parse("FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if got := never.Between(dtstart, nil, dtstart.Add(-time.Second), farFuture, 10); len(got) != 0 {
+		t.Errorf("Between() = %v for the 30th of February", got)
+	}
+
+	if _, ok := never.After(dtstart, dtstart); ok {
+		t.Error("After() found a 30th of February")
+	}
+
+	if n := never.CountBefore(dtstart, farFuture); n != 0 {
+		t.Errorf("CountBefore() = %d for the 30th of February", n)
+	}
+}
+
+func TestAfterAndCountBefore(t *testing.T) {
+	r, err := Parse("FREQ=WEEKLY;COUNT=10;WKST=SU;BYDAY=TU,TH")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	dtstart := local(t, "19970902T090000")[0]
+
+	next, ok := r.After(dtstart, local(t, "19970912T000000")[0])
+	if !ok || !next.Equal(local(t, "19970916T090000")[0]) {
+		t.Errorf("After() = %v, %v, want 16 September", next, ok)
+	}
+
+	if _, ok := r.After(dtstart, local(t, "19971002T090000")[0]); ok {
+		t.Error("After() found an occurrence past COUNT")
+	}
+
+	if n := r.CountBefore(dtstart, local(t, "19970916T090000")[0]); n != 4 {
+		t.Errorf("CountBefore() = %d, want 4", n)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+		err  bool
+	}{
+		{rule: "RRULE:FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10", want: "FREQ=WEEKLY;COUNT=10;BYDAY=TU,TH"},
+		{rule: "freq=monthly;byday=+1fr,-1su", want: "FREQ=MONTHLY;BYDAY=1FR,-1SU"},
+		{rule: "FREQ=DAILY;INTERVAL=1", want: "FREQ=DAILY"},
+		{rule: "FREQ=DAILY;UNTIL=19971224", want: "FREQ=DAILY;UNTIL=19971224T235959Z"},
+		{rule: "FREQ=WEEKLY;WKST=SU;BYDAY=MO", want: "FREQ=WEEKLY;BYDAY=MO;WKST=SU"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=1,-1", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{rule: "", err: true},
+		{rule: "COUNT=3", err: true},
+		{rule: "FREQ=HOURLY", err: true},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=19971224T000000Z", err: true},
+		{rule: "FREQ=DAILY;COUNT=3;COUNT=4", err: true},
+		{rule: "FREQ=DAILY;COUNT=0", err: true},
+		{rule: "FREQ=MONTHLY;BYSETPOS=-1;BYDAY=MO", err: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=0", err: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=32", err: true},
+		{rule: "FREQ=MONTHLY;BYDAY=6MO", err: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", err: true},
+		{rule: "FREQ=WEEKLY;BYMONTHDAY=1", err: true},
+		{rule: "FREQ=YEARLY;BYDAY=MO", err: true},
+		{rule: "FREQ=DAILY;BYDAY=XX", err: true},
+		{rule: "FREQ=DAILY;UNTIL=tomorrow", err: true},
+	}
+
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if tt.err {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalid", tt.rule, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.rule, err)
+			continue
+		}
+
+		if got := r.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.rule, got, tt.want)
+		}
+
+		if again, err := Parse(r.String()); err != nil || again.String() != tt.want {
+			t.Errorf("Parse(%q) does not read its own output back: %v", r.String(), err)
+		}
+	}
+}
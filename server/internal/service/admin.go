@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+)
+
+const maxUsersPageSize = 100
+
+func (s *Service) ListUsers(ctx context.Context, limit, offset int) ([]user.User, error) {
+	const op = "service.ListUsers"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	if limit <= 0 || limit > maxUsersPageSize {
+		limit = maxUsersPageSize
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	users, err := s.usrRepo.GetAllUsers(ctx, limit, offset)
+	if err != nil {
+		log.Error("error retrieving users", slog.String("err", err.Error()))
+		return nil, fmt.Errorf("error retrieving users")
+	}
+
+	return users, nil
+}
+
+// SetUserRole changes the role of a user. The new role applies to the user's
+// next request, whatever tokens the user holds.
+func (s *Service) SetUserRole(ctx context.Context, userID uuid.UUID, role user.Role) error {
+	const op = "service.SetUserRole"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+		slog.String("role", string(role)),
+	)
+
+	if !role.IsValid() {
+		return ErrInvalidRole
+	}
+
+	err := s.usrRepo.SetUserRole(ctx, userID, role)
+	if err != nil {
+		log.Error("error setting role", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNoUser) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error setting role")
+	}
+
+	log.Info("role changed")
+
+	return nil
+}
+
+// PromoteAdmin makes the account registered with the email an admin, so the
+// first admin does not have to be set in the database by hand. An email that
+// is not registered yet is promoted on the next start.
+func (s *Service) PromoteAdmin(ctx context.Context, email string) error {
+	const op = "service.PromoteAdmin"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	usr, err := s.usrRepo.GetUser(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrorNoUser) {
+			log.Warn("admin is not registered yet")
+			return nil
+		}
+		log.Error("error retrieving user", slog.String("err", err.Error()))
+		return fmt.Errorf("error retrieving user")
+	}
+
+	if usr.Role == user.RoleAdmin {
+		return nil
+	}
+
+	if err := s.usrRepo.SetUserRole(ctx, usr.ID, user.RoleAdmin); err != nil {
+		log.Error("error setting role", slog.String("err", err.Error()))
+		return fmt.Errorf("error setting role")
+	}
+
+	log.Info("admin promoted")
+
+	return nil
+}
+
+// RemoveUser deletes any account without asking for its password. The
+// consequences are the same as for DeleteAccount.
+func (s *Service) RemoveUser(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.RemoveUser"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	if err := s.cancelCreatedEvents(ctx, userID); err != nil {
+		log.Error("error cancelling created events", slog.String("err", err.Error()))
+		return fmt.Errorf("error deleting user")
+	}
+
+	promoted, err := s.usrRepo.DeleteUser(ctx, userID)
+	if err != nil {
+		log.Error("error deleting user", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNoUser) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error deleting user")
+	}
+
+	s.notifyPromoted(ctx, promoted)
+
+	log.Info("user removed")
+
+	return nil
+}
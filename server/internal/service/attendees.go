@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/google/uuid"
+)
+
+const maxAttendeesPageSize = 100
+
+// ListAttendees returns a page of the enrollments of the event if the user is
+// its creator or an admin.
+func (s *Service) ListAttendees(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID, limit, offset int) ([]enrollment.Attendee, error) {
+	const op = "service.ListAttendees"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	if err := s.authorizeEventOwner(ctx, userID, role, eventID); err != nil {
+		log.Error("user may not see the enrollments", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	if limit <= 0 || limit > maxAttendeesPageSize {
+		limit = maxAttendeesPageSize
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	attendees, err := s.evtRepo.GetAttendees(ctx, eventID, limit, offset)
+	if err != nil {
+		log.Error("error retrieving enrollments", slog.String("err", err.Error()))
+		return nil, fmt.Errorf("error retrieving enrollments")
+	}
+
+	return attendees, nil
+}
+
+// ExportAttendees calls fn for every enrollment of the event if the user is
+// its creator or an admin. Nothing is passed to fn when the access is denied.
+func (s *Service) ExportAttendees(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID, fn func(enrollment.Attendee) error) error {
+	const op = "service.ExportAttendees"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	if err := s.authorizeEventOwner(ctx, userID, role, eventID); err != nil {
+		log.Error("user may not export the enrollments", slog.String("err", err.Error()))
+		return err
+	}
+
+	if err := s.evtRepo.StreamAttendees(ctx, eventID, 0, 0, fn); err != nil {
+		log.Error("error exporting enrollments", slog.String("err", err.Error()))
+		return fmt.Errorf("error exporting enrollments")
+	}
+
+	return nil
+}
+
+// authorizeEventOwner returns nil if the user is the creator of the event or
+// an admin.
+func (s *Service) authorizeEventOwner(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) error {
+	evt, err := s.evtRepo.GetEvent(ctx, eventID)
+	if err != nil {
+		return ErrEventNotFound
+	}
+
+	if evt.CreatorID != userID && role != user.RoleAdmin {
+		return ErrForbidden
+	}
+
+	return nil
+}
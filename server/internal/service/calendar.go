@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/ical"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+)
+
+// calendarFeedHistory is how long past events stay in the feed.
+const calendarFeedHistory = 30 * 24 * time.Hour
+
+// RegenerateCalendarFeed issues a new secret feed link for the user. A link
+// issued before stops working. Only the hash of the token is stored, so the
+// link can be seen only once.
+func (s *Service) RegenerateCalendarFeed(ctx context.Context, userID uuid.UUID) (string, error) {
+	const op = "service.RegenerateCalendarFeed"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	token, err := generateSecretToken()
+	if err != nil {
+		log.Error("error generating feed token", slog.String("err", err.Error()))
+		return "", fmt.Errorf("error creating calendar feed")
+	}
+
+	if err := s.usrRepo.SetCalendarToken(ctx, userID, hashToken(token)); err != nil {
+		log.Error("error saving feed token", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNoUser) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("error creating calendar feed")
+	}
+
+	log.Info("calendar feed link issued")
+
+	return fmt.Sprintf("%s/calendar/%s.ics", s.urls.Public, url.PathEscape(token)), nil
+}
+
+func (s *Service) RevokeCalendarFeed(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.RevokeCalendarFeed"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	if err := s.usrRepo.DeleteCalendarToken(ctx, userID); err != nil {
+		log.Error("error revoking calendar feed", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorFeedNotFound) {
+			return ErrFeedNotFound
+		}
+		return fmt.Errorf("error revoking calendar feed")
+	}
+
+	return nil
+}
+
+// GetCalendarFeed renders the events the owner of the token has a confirmed
+// seat at or has created, from calendarFeedHistory ago on. Waitlisted events
+// are left out.
+func (s *Service) GetCalendarFeed(ctx context.Context, token string) ([]byte, error) {
+	const op = "service.GetCalendarFeed"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	userID, err := s.usrRepo.GetUserByCalendarToken(ctx, hashToken(token))
+	if err != nil {
+		log.Error("error getting calendar feed", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorFeedNotFound) {
+			return nil, ErrFeedNotFound
+		}
+		return nil, fmt.Errorf("error getting calendar feed")
+	}
+
+	log = log.With(slog.String("user_id", userID.String()))
+
+	after := time.Now().Add(-calendarFeedHistory)
+
+	confirmed := enrollment.StatusConfirmed
+
+	visited, err := s.evtRepo.GetAllEvents(ctx, event.Filter{After: &after, VisitorID: &userID, VisitorStatus: &confirmed})
+	if err != nil {
+		log.Error("error retrieving enrolled events", slog.String("err", err.Error()))
+		return nil, fmt.Errorf("error getting calendar feed")
+	}
+
+	created, err := s.evtRepo.GetAllEvents(ctx, event.Filter{After: &after, CreatorID: &userID})
+	if err != nil {
+		log.Error("error retrieving created events", slog.String("err", err.Error()))
+		return nil, fmt.Errorf("error getting calendar feed")
+	}
+
+	cal := ical.Calendar{
+		Method: ical.MethodPublish,
+		Name:   "SPDA",
+	}
+
+	seen := make(map[uuid.UUID]bool, len(visited.Events)+len(created.Events))
+	organizers := make(map[uuid.UUID]ical.Person)
+
+	for _, evt := range append(visited.Events, created.Events...) {
+		if seen[evt.ID] {
+			continue
+		}
+		seen[evt.ID] = true
+
+		organizer, ok := organizers[evt.CreatorID]
+		if !ok {
+			organizer = s.organizerOf(ctx, evt)
+			organizers[evt.CreatorID] = organizer
+		}
+
+		cal.Events = append(cal.Events, calendarEvent(evt, s.cachedLocation(ctx, evt.Location), organizer))
+	}
+
+	return cal.Encode(), nil
+}
+
+// cachedLocation is describeLocation without the geocoder. Calendar clients
+// poll feeds often and a feed may hold many events, so only addresses that
+// are already known are used.
+func (s *Service) cachedLocation(ctx context.Context, point orb.Point) string {
+	if s.cache != nil {
+		if location, err := s.cache.GetLocation(ctx, point); err == nil {
+			return location
+		}
+	}
+
+	return fmt.Sprintf("%f, %f", point.Lat(), point.Lon())
+}
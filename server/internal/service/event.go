@@ -9,37 +9,118 @@ import (
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
 	"github.com/google/uuid"
-	"github.com/paulmach/orb"
 )
 
 type EventRepository interface {
 	InsertEvent(ctx context.Context, evt *event.Event) error
 	DeleteEvent(ctx context.Context, id uuid.UUID) error
 	GetEvent(ctx context.Context, id uuid.UUID) (event.Event, error)
-	GetAllEvents(ctx context.Context, limit, offset *int, eventType *int, creatorEmail *string, before, after *time.Time, location *orb.Point, radius *float64, visitorEmail *string) ([]event.Event, error)
-	SubscribeToEvent(ctx context.Context, eventId uuid.UUID, email string) (uuid.UUID, error)
-	UnsubscribeFromEvent(ctx context.Context, enrollmentId uuid.UUID) error
-	GetEventSubscription(ctx context.Context, enrollmentId uuid.UUID, email string) (enrollment.Enrollment, error)
-	UpdateEvent(ctx context.Context, evt event.Event) error
+	GetEvents(ctx context.Context, ids []uuid.UUID) ([]event.Event, error)
+	GetAllEvents(ctx context.Context, filter event.Filter) (event.Page, error)
+	SubscribeToEvent(ctx context.Context, eventId uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, error)
+	UnsubscribeFromEvent(ctx context.Context, enrollmentId uuid.UUID) ([]enrollment.Enrollment, error)
+	GetEventSubscription(ctx context.Context, enrollmentId uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, error)
+	UpdateEvent(ctx context.Context, evt event.Event) (event.Event, []enrollment.Enrollment, error)
 	GetAllSubscriptions(ctx context.Context, eventId uuid.UUID) ([]enrollment.Enrollment, error)
+	GetWaitlistPosition(ctx context.Context, enrollmentID uuid.UUID) (int, error)
+	GetWaitlist(ctx context.Context, eventID uuid.UUID) ([]enrollment.Attendee, error)
+	GetAttendees(ctx context.Context, eventID uuid.UUID, limit, offset int) ([]enrollment.Attendee, error)
+	StreamAttendees(ctx context.Context, eventID uuid.UUID, limit, offset int, fn func(enrollment.Attendee) error) error
+	QueueEventChange(ctx context.Context, old event.Event, delay, maxDelay time.Duration) error
+	ClaimEventChanges(ctx context.Context, limit int) ([]event.PendingChange, error)
+	RestoreEventChange(ctx context.Context, change event.PendingChange, retryIn time.Duration) error
+	CancelEvent(ctx context.Context, id uuid.UUID, reason *string) (int, error)
+	PublishEvent(ctx context.Context, id uuid.UUID) error
+	PublishDueEvents(ctx context.Context) ([]uuid.UUID, error)
+	InsertSeries(ctx context.Context, series *event.Series, starts []time.Time) ([]event.Event, error)
+	GetSeries(ctx context.Context, id uuid.UUID) (event.Series, error)
+	GetSeriesToExtend(ctx context.Context, until time.Time, limit int) ([]event.Series, error)
+	ExtendSeries(ctx context.Context, series event.Series, starts []time.Time, until time.Time, finished bool) error
+	SubscribeToSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) ([]enrollment.Enrollment, error)
+	UnsubscribeFromSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) ([]enrollment.Enrollment, error)
+	UpdateFollowing(ctx context.Context, evt event.Event, shift time.Duration, tail event.Series, headRule *string) ([]event.Revision, []enrollment.Enrollment, error)
+	GetEventTypes(ctx context.Context) ([]event.Type, error)
+	GetEventType(ctx context.Context, id int) (event.Type, error)
+	InsertEventType(ctx context.Context, t *event.Type) error
+	UpdateEventType(ctx context.Context, t event.Type) error
+	DeleteEventType(ctx context.Context, id int) error
 }
 
-func (s *Service) CreateEvent(ctx context.Context, evt event.Event) (event.Event, error) {
+// defaultEventDuration is how long events created without an end last.
+const defaultEventDuration = time.Hour
+
+// setEventTimes fills in the end and the zone of an event created without
+// them.
+func setEventTimes(evt *event.Event) {
+	if evt.TimeZone == "" {
+		evt.TimeZone = event.DefaultTimeZone
+	}
+	if evt.EndsAt.IsZero() {
+		evt.EndsAt = evt.Date.Add(defaultEventDuration)
+	}
+}
+
+// keepEventTimes keeps the zone and the duration of the old version of the
+// event when the edit leaves them out.
+func keepEventTimes(evt *event.Event, old event.Event) {
+	if evt.TimeZone == "" {
+		evt.TimeZone = old.TimeZone
+	}
+	if evt.EndsAt.IsZero() {
+		evt.EndsAt = evt.Date.Add(old.Duration())
+	}
+}
+
+// keepEventTags keeps the tags of the old version of the event when the edit
+// leaves them out. An empty list removes them.
+func keepEventTags(evt *event.Event, old event.Event) {
+	if evt.Tags == nil {
+		evt.Tags = old.Tags
+	}
+	evt.Tags = event.NormalizeTags(evt.Tags)
+}
+
+// CreateEvent stores a new event on behalf of the authenticated user, who
+// becomes its creator. Events are published right away unless they are
+// drafts.
+func (s *Service) CreateEvent(ctx context.Context, creatorID uuid.UUID, evt event.Event) (event.Event, error) {
 	const op = "service.CreateEvent"
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("creator_email", evt.CreatorEmail),
+		slog.String("creator_id", creatorID.String()),
 	)
 
-	_, err := s.usrRepo.GetUser(ctx, evt.CreatorEmail)
+	creator, err := s.usrRepo.GetUserByID(ctx, creatorID)
 	if err != nil {
 		log.Error("creator not found", slog.String("error", err.Error()))
 		return event.Event{}, ErrUserNotFound
 	}
 
+	evt.CreatorID = creator.ID
+
+	if err := s.requireVerified(creator); err != nil {
+		log.Error("creator has not verified email")
+		return event.Event{}, err
+	}
+
+	if evt.Status != event.StatusDraft {
+		now := time.Now()
+		evt.Status = event.StatusPublished
+		evt.PublishAt = &now
+	}
+
+	if err := s.requireEventType(ctx, evt.Type); err != nil {
+		log.Error("error checking event type", slog.String("error", err.Error()))
+		return event.Event{}, err
+	}
+
+	setEventTimes(&evt)
+	evt.Tags = event.NormalizeTags(evt.Tags)
+
 	err = s.evtRepo.InsertEvent(ctx, &evt)
 	if err != nil {
 		log.Error("error inserting event", slog.String("error", err.Error()))
@@ -49,107 +130,97 @@ func (s *Service) CreateEvent(ctx context.Context, evt event.Event) (event.Event
 	return evt, nil
 }
 
-func (s *Service) UpdateEvent(ctx context.Context, evt event.Event) error {
+// UpdateEvent replaces the event if the user is its creator or an admin. The
+// creator of the event never changes. Unless the edit is silent, the enrolled
+// users get a letter about it; edits made shortly one after another are
+// described in a single letter.
+func (s *Service) UpdateEvent(ctx context.Context, userID uuid.UUID, role user.Role, evt event.Event, notify bool) (event.Event, error) {
 	const op = "service.UpdateEvent"
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("creator_email", evt.CreatorEmail),
+		slog.String("user_id", userID.String()),
 		slog.String("event_id", evt.ID.String()),
+		slog.Bool("notify", notify),
 	)
 
-	_, err := s.usrRepo.GetUser(ctx, evt.CreatorEmail)
-	if err != nil {
-		log.Error("creator not found", slog.String("error", err.Error()))
-		return ErrUserNotFound
-	}
-
-	evt.AvailableSeats = evt.TotalSeats
-
 	oldEvent, err := s.evtRepo.GetEvent(ctx, evt.ID)
 	if err != nil {
-		return ErrEventNotFound
+		log.Error("error getting event", slog.String("error", err.Error()))
+		return event.Event{}, ErrEventNotFound
 	}
 
-	enrolledUsers, err := s.evtRepo.GetAllSubscriptions(ctx, evt.ID)
-	if err != nil {
-		log.Error("could not retrieve enrollments", slog.String("err", err.Error()))
-		return fmt.Errorf("could not retrieve enrollments")
+	if oldEvent.CreatorID != userID && role != user.RoleAdmin {
+		log.Error("user is not the creator of the event")
+		return event.Event{}, ErrForbidden
 	}
 
-	if evt.HasUnlimitedSeats != "true" {
-
-		if len(enrolledUsers) > evt.TotalSeats {
-			return ErrNotEnoughSeats
-		}
+	if evt.PublishAt != nil && oldEvent.Status != event.StatusDraft {
+		log.Error("only drafts can be scheduled")
+		return event.Event{}, ErrNotDraft
+	}
 
-		evt.AvailableSeats -= len(enrolledUsers)
+	if err := s.requireEventType(ctx, evt.Type); err != nil {
+		log.Error("error checking event type", slog.String("error", err.Error()))
+		return event.Event{}, err
 	}
 
-	err = s.evtRepo.UpdateEvent(ctx, evt)
+	evt.CreatorID = oldEvent.CreatorID
+	keepEventTimes(&evt, oldEvent)
+	keepEventTags(&evt, oldEvent)
+
+	evt, promoted, err := s.evtRepo.UpdateEvent(ctx, evt)
 	if err != nil {
 		log.Error("could not update event", slog.String("error", err.Error()))
 		switch {
 		default:
-			return fmt.Errorf("error updating event")
+			return event.Event{}, fmt.Errorf("error updating event")
 		case errors.Is(err, storage.ErrorEventNotFound):
-			return ErrEventNotFound
+			return event.Event{}, ErrEventNotFound
+		case errors.Is(err, storage.ErrorNotEnoughSeats):
+			return event.Event{}, ErrNotEnoughSeats
+		case errors.Is(err, storage.ErrorEventCancelled):
+			return event.Event{}, ErrEventCancelled
 		}
 	}
 
-	for _, enrollment := range enrolledUsers {
-		enrollment := enrollment
-		user, err := s.usrRepo.GetUser(ctx, enrollment.UserEmail)
-		if err != nil {
-			return fmt.Errorf("op: %s, err: %v", op, err)
-		}
-
-		var newCtx context.Context
-		var cancel context.CancelFunc
+	s.notifyPromoted(ctx, promoted)
 
-		deadline, ok := ctx.Deadline()
-		if ok {
-			newCtx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline)
-			_ = cancel
-		} else {
-			newCtx = context.WithoutCancel(ctx)
+	if notify && len(event.Diff(oldEvent, evt)) > 0 {
+		if err := s.evtRepo.QueueEventChange(ctx, oldEvent, changeBatchDelay, changeBatchMaxDelay); err != nil {
+			log.Error("could not queue change notifications", slog.String("err", err.Error()))
 		}
-
-		go s.SendEventChangesNotificationEmail(
-			newCtx,
-			oldEvent,
-			evt,
-			user.Name,
-			user.Email,
-		)
 	}
 
-	return nil
+	return evt, nil
 }
 
-func (s *Service) DeleteEvent(ctx context.Context, eventID uuid.UUID) error {
+// DeleteEvent deletes the event if the user is its creator or an admin. The
+// enrolled users are told about it.
+func (s *Service) DeleteEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) error {
 	const op = "service.DeleteEvent"
 
 	log := s.log.With(
 		slog.String("op", op),
 		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
 	)
 
-	email, ok := ctx.Value("email").(string)
-	if !ok || email == "" {
-		log.Error("email not found in context")
-		return fmt.Errorf("email not found in context")
-	}
-
 	evt, err := s.evtRepo.GetEvent(ctx, eventID)
 	if err != nil {
 		log.Error("error getting event", slog.String("error", err.Error()))
-		return fmt.Errorf("event not found")
+		return ErrEventNotFound
 	}
 
-	if evt.CreatorEmail != email {
+	if evt.CreatorID != userID && role != user.RoleAdmin {
 		log.Error("user is not the creator of the event")
-		return fmt.Errorf("user is not the creator of this event")
+		return ErrForbidden
+	}
+
+	enrollments, err := s.evtRepo.GetAllSubscriptions(ctx, eventID)
+	if err != nil {
+		log.Error("could not retrieve enrollments", slog.String("err", err.Error()))
+		return fmt.Errorf("could not retrieve enrollments")
 	}
 
 	err = s.evtRepo.DeleteEvent(ctx, eventID)
@@ -158,10 +229,68 @@ func (s *Service) DeleteEvent(ctx context.Context, eventID uuid.UUID) error {
 		return fmt.Errorf("error deleting event")
 	}
 
+	// a cancellation has to be newer than the last invite
+	evt.Sequence++
+	s.notifyCancelled(ctx, evt, enrollments)
+
 	return nil
 }
 
-func (s *Service) GetEvent(ctx context.Context, eventID uuid.UUID) (event.Event, error) {
+// CancelEvent cancels the event if the user is its creator or an admin.
+// Unlike deletion the event and its enrollments stay readable. Everyone
+// enrolled is emailed, with the reason if one is given.
+func (s *Service) CancelEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID, reason *string) (event.Event, error) {
+	const op = "service.CancelEvent"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	evt, err := s.evtRepo.GetEvent(ctx, eventID)
+	if err != nil {
+		log.Error("error getting event", slog.String("error", err.Error()))
+		return event.Event{}, ErrEventNotFound
+	}
+
+	if evt.CreatorID != userID && role != user.RoleAdmin {
+		log.Error("user is not the creator of the event")
+		return event.Event{}, ErrForbidden
+	}
+
+	evt.Sequence, err = s.evtRepo.CancelEvent(ctx, eventID, reason)
+	if err != nil {
+		log.Error("error cancelling event", slog.String("error", err.Error()))
+		switch {
+		default:
+			return event.Event{}, fmt.Errorf("error cancelling event")
+		case errors.Is(err, storage.ErrorEventNotFound):
+			return event.Event{}, ErrEventNotFound
+		case errors.Is(err, storage.ErrorEventCancelled):
+			return event.Event{}, ErrEventCancelled
+		}
+	}
+
+	evt.Status = event.StatusCancelled
+	evt.CancellationReason = reason
+
+	enrollments, err := s.evtRepo.GetAllSubscriptions(ctx, eventID)
+	if err != nil {
+		log.Error("could not retrieve enrollments", slog.String("err", err.Error()))
+		return evt, nil
+	}
+
+	s.notifyCancelled(ctx, evt, enrollments)
+
+	log.Info("event cancelled", slog.Int("enrollments", len(enrollments)))
+
+	return evt, nil
+}
+
+// GetEvent returns the event. Drafts are only shown to their creator and
+// admins, anonymous users come with uuid.Nil.
+func (s *Service) GetEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) (event.Event, error) {
 	const op = "service.GetEvent"
 
 	log := s.log.With(
@@ -172,105 +301,161 @@ func (s *Service) GetEvent(ctx context.Context, eventID uuid.UUID) (event.Event,
 	evt, err := s.evtRepo.GetEvent(ctx, eventID)
 	if err != nil {
 		log.Error("error getting event", slog.String("error", err.Error()))
-		return event.Event{}, fmt.Errorf("event not found")
+		return event.Event{}, ErrEventNotFound
+	}
+
+	// the draft is not found rather than forbidden, so it does not leak
+	if evt.Status == event.StatusDraft && evt.CreatorID != userID && role != user.RoleAdmin {
+		log.Error("draft is hidden from the user", slog.String("user_id", userID.String()))
+		return event.Event{}, ErrEventNotFound
 	}
 
 	return evt, nil
 }
 
-func (s *Service) GetAllEvents(
-	ctx context.Context,
-	limit, offset *int,
-	eventType *int,
-	creatorEmail *string,
-	before, after *time.Time,
-	location *orb.Point,
-	radius *float64,
-	visitorEmail *string,
-) ([]event.Event, error) {
+// GetAllEvents lists a page of the events matching the filter. Drafts are
+// never listed unless asked for, and then only the user's own, or anyone's
+// for admins. The same holds for listing the events a user is enrolled in.
+func (s *Service) GetAllEvents(ctx context.Context, userID uuid.UUID, role user.Role, filter event.Filter) (event.Page, error) {
 	const op = "service.GetAllEvents"
 
 	log := s.log.With(
 		slog.String("op", op),
 	)
 
-	events, err := s.evtRepo.GetAllEvents(
-		ctx,
-		limit,
-		offset,
-		eventType,
-		creatorEmail,
-		before,
-		after,
-		location,
-		radius,
-		visitorEmail,
-	)
+	if filter.Status != nil && *filter.Status == event.StatusDraft && role != user.RoleAdmin {
+		if userID == uuid.Nil || (filter.CreatorID != nil && *filter.CreatorID != userID) {
+			log.Error("user may not list these drafts")
+			return event.Page{}, ErrForbidden
+		}
+		filter.CreatorID = &userID
+	}
+
+	// whom a user enrolled with is private to the user
+	if filter.VisitorID != nil && *filter.VisitorID != userID && role != user.RoleAdmin {
+		log.Error("user may not list the enrollments of another user")
+		return event.Page{}, ErrForbidden
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = event.SortDate
+	}
+
+	page, err := s.evtRepo.GetAllEvents(ctx, filter)
 	if err != nil {
 		log.Error("error retrieving events", slog.String("error", err.Error()))
-		return nil, fmt.Errorf("error retrieving events")
+		return event.Page{}, fmt.Errorf("error retrieving events")
 	}
 
-	return events, nil
+	return page, nil
 }
 
-func (s *Service) SubscribeToEvent(ctx context.Context, eventId uuid.UUID, email string) (uuid.UUID, error) {
-	const op = "service.SubscribeToEvent"
-
-	var id uuid.UUID
+// PublishEvent publishes the draft right away if the user is its creator or
+// an admin.
+func (s *Service) PublishEvent(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) (event.Event, error) {
+	const op = "service.PublishEvent"
 
 	log := s.log.With(
-		slog.String("event_id", eventId.String()),
-		slog.String("email", email),
 		slog.String("op", op),
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
 	)
 
-	_, err := s.usrRepo.GetUser(ctx, email)
+	if err := s.authorizeEventOwner(ctx, userID, role, eventID); err != nil {
+		log.Error("user may not publish the event", slog.String("err", err.Error()))
+		return event.Event{}, err
+	}
+
+	if err := s.evtRepo.PublishEvent(ctx, eventID); err != nil {
+		log.Error("error publishing event", slog.String("error", err.Error()))
+		switch {
+		default:
+			return event.Event{}, fmt.Errorf("error publishing event")
+		case errors.Is(err, storage.ErrorEventNotFound):
+			return event.Event{}, ErrEventNotFound
+		case errors.Is(err, storage.ErrorNotDraft):
+			return event.Event{}, ErrNotDraft
+		}
+	}
+
+	evt, err := s.evtRepo.GetEvent(ctx, eventID)
 	if err != nil {
-		log.Error("error retrieving user", slog.String("err", err.Error()))
-		return uuid.UUID{}, fmt.Errorf("error retrieving user")
+		log.Error("error getting event", slog.String("error", err.Error()))
+		return event.Event{}, ErrEventNotFound
 	}
 
-	evt, err := s.evtRepo.GetEvent(ctx, eventId)
+	log.Info("event published")
+
+	return evt, nil
+}
+
+// SubscribeToEvent enrolls the user in the event. When the event is sold out
+// the enrollment is put on the waitlist, otherwise a confirmation with a
+// calendar invite is emailed.
+func (s *Service) SubscribeToEvent(ctx context.Context, eventId uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, error) {
+	const op = "service.SubscribeToEvent"
+
+	log := s.log.With(
+		slog.String("event_id", eventId.String()),
+		slog.String("user_id", userID.String()),
+		slog.String("op", op),
+	)
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		log.Error("error retrieving event", slog.String("err", err.Error()))
-		return uuid.UUID{}, fmt.Errorf("error retrieving event")
+		log.Error("error retrieving user", slog.String("err", err.Error()))
+		return enrollment.Enrollment{}, fmt.Errorf("error retrieving user")
 	}
 
-	if evt.AvailableSeats < 1 {
-		log.Error("not enough free spaces on the event")
-		return uuid.UUID{}, fmt.Errorf("not enough free spaces on the event")
+	if err := s.requireVerified(usr); err != nil {
+		log.Error("user has not verified email")
+		return enrollment.Enrollment{}, err
 	}
 
-	id, err = s.evtRepo.SubscribeToEvent(ctx, eventId, email)
+	enrlmnt, err := s.evtRepo.SubscribeToEvent(ctx, eventId, userID)
 	if err != nil {
 		log.Error("error making an appointment", slog.String("err", err.Error()))
-		return uuid.UUID{}, fmt.Errorf("error making an appointment")
+		switch {
+		default:
+			return enrollment.Enrollment{}, fmt.Errorf("error making an appointment")
+		case errors.Is(err, storage.ErrorEventNotFound):
+			return enrollment.Enrollment{}, ErrEventNotFound
+		case errors.Is(err, storage.ErrorAlreadyEnrolled):
+			return enrollment.Enrollment{}, ErrAlreadyEnrolled
+		case errors.Is(err, storage.ErrorEventClosed):
+			return enrollment.Enrollment{}, ErrEventClosed
+		}
 	}
 
-	return id, nil
+	s.notifyEnrolled(ctx, usr, enrlmnt)
+
+	return enrlmnt, nil
 }
 
-func (s *Service) UnsibscribeFromEvent(ctx context.Context, eventId uuid.UUID, email string) error {
+// UnsibscribeFromEvent deletes the enrollment of the user. A freed seat goes
+// to the first user on the waitlist, who is notified by email.
+func (s *Service) UnsibscribeFromEvent(ctx context.Context, eventId uuid.UUID, userID uuid.UUID) error {
 	const op = "service.UnsibscribeFromEvent"
 
 	log := s.log.With(
-		slog.String("email", email),
+		slog.String("user_id", userID.String()),
 		slog.String("event_id", eventId.String()),
 		slog.String("op", op),
 	)
 
-	enrlmnt, err := s.evtRepo.GetEventSubscription(ctx, eventId, email)
+	enrlmnt, err := s.evtRepo.GetEventSubscription(ctx, eventId, userID)
 	if err != nil {
 		log.Error("error accessing event enrollment", slog.String("err", err.Error()))
 		return fmt.Errorf("error accessing event enrollment")
 	}
 
-	err = s.evtRepo.UnsubscribeFromEvent(ctx, enrlmnt.Id)
+	promoted, err := s.evtRepo.UnsubscribeFromEvent(ctx, enrlmnt.Id)
 	if err != nil {
 		log.Error("error deleting enrollment", slog.Any("enrollment", enrlmnt))
 		return fmt.Errorf("error deleting enrollment")
 	}
 
+	s.notifyPromoted(ctx, promoted)
+
 	return nil
 }
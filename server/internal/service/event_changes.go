@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+)
+
+const (
+	// changeBatchDelay is how long a change letter waits for further edits.
+	// Every edit within the delay starts it again.
+	changeBatchDelay = 2 * time.Minute
+	// changeBatchMaxDelay bounds the wait for events that are edited over
+	// and over.
+	changeBatchMaxDelay = 15 * time.Minute
+	changePollInterval  = 30 * time.Second
+	changeRetryDelay    = 5 * time.Minute
+	changeClaimLimit    = 16
+)
+
+// NotifyEventChanges sends the letters about edited events once no more
+// edits come in, until done is closed. Any number of replicas can run it.
+func (s *Service) NotifyEventChanges(done <-chan struct{}) {
+	const op = "service.NotifyEventChanges"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("starting event change notifications")
+
+	ticker := time.NewTicker(changePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			changes, err := s.evtRepo.ClaimEventChanges(context.Background(), changeClaimLimit)
+			if err != nil {
+				log.Error("error claiming event changes", slog.String("err", err.Error()))
+				continue
+			}
+
+			for _, change := range changes {
+				if err := s.sendEventChanges(context.Background(), change); err != nil {
+					log.Error("error notifying users of event changes",
+						slog.String("event_id", change.EventID.String()),
+						slog.String("err", err.Error()),
+					)
+
+					err := s.evtRepo.RestoreEventChange(context.Background(), change, changeRetryDelay)
+					if err != nil && !errors.Is(err, storage.ErrorEventNotFound) {
+						log.Error("error restoring event change", slog.String("err", err.Error()))
+					}
+				}
+			}
+		}
+	}
+}
+
+// sendEventChanges compares the event before the batch of edits with the
+// event now. Users who enrolled after the first edit have never seen the old
+// version and are not told about it.
+func (s *Service) sendEventChanges(ctx context.Context, change event.PendingChange) error {
+	evt, err := s.evtRepo.GetEvent(ctx, change.EventID)
+	if err != nil {
+		// deleted events get a cancellation letter instead
+		if errors.Is(err, storage.ErrorEventNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	enrollments, err := s.evtRepo.GetAllSubscriptions(ctx, change.EventID)
+	if err != nil {
+		return err
+	}
+
+	informed := make([]enrollment.Enrollment, 0, len(enrollments))
+	for _, enrlmnt := range enrollments {
+		if enrlmnt.CreatedAt.Before(change.CreatedAt) {
+			informed = append(informed, enrlmnt)
+		}
+	}
+
+	return s.notifyEventChanges(ctx, change.Old, evt, informed)
+}
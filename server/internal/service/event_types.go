@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+)
+
+// GetEventTypes lists every event type with its names.
+func (s *Service) GetEventTypes(ctx context.Context) ([]event.Type, error) {
+	const op = "service.GetEventTypes"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	types, err := s.evtRepo.GetEventTypes(ctx)
+	if err != nil {
+		log.Error("error retrieving event types", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("error retrieving event types")
+	}
+
+	return types, nil
+}
+
+func (s *Service) CreateEventType(ctx context.Context, t event.Type) (event.Type, error) {
+	const op = "service.CreateEventType"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	if err := s.evtRepo.InsertEventType(ctx, &t); err != nil {
+		log.Error("error inserting event type", slog.String("error", err.Error()))
+		return event.Type{}, fmt.Errorf("error creating event type")
+	}
+
+	log.Info("event type created", slog.Int("type_id", t.ID))
+
+	return t, nil
+}
+
+// UpdateEventType replaces the names of the type.
+func (s *Service) UpdateEventType(ctx context.Context, t event.Type) error {
+	const op = "service.UpdateEventType"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("type_id", t.ID),
+	)
+
+	if err := s.evtRepo.UpdateEventType(ctx, t); err != nil {
+		log.Error("error updating event type", slog.String("error", err.Error()))
+		if errors.Is(err, storage.ErrorEventTypeNotFound) {
+			return ErrEventTypeNotFound
+		}
+		return fmt.Errorf("error updating event type")
+	}
+
+	return nil
+}
+
+// DeleteEventType deletes the type unless some event has it.
+func (s *Service) DeleteEventType(ctx context.Context, id int) error {
+	const op = "service.DeleteEventType"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("type_id", id),
+	)
+
+	if err := s.evtRepo.DeleteEventType(ctx, id); err != nil {
+		log.Error("error deleting event type", slog.String("error", err.Error()))
+		switch {
+		default:
+			return fmt.Errorf("error deleting event type")
+		case errors.Is(err, storage.ErrorEventTypeNotFound):
+			return ErrEventTypeNotFound
+		case errors.Is(err, storage.ErrorEventTypeInUse):
+			return ErrEventTypeInUse
+		}
+	}
+
+	log.Info("event type deleted")
+
+	return nil
+}
+
+// requireEventType fails with ErrEventTypeNotFound unless the type exists.
+func (s *Service) requireEventType(ctx context.Context, id int) error {
+	if _, err := s.evtRepo.GetEventType(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrorEventTypeNotFound) {
+			return ErrEventTypeNotFound
+		}
+		return err
+	}
+
+	return nil
+}
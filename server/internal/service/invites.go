@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/ical"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+)
+
+const inviteFileName = "invite.ics"
+
+// newInvite builds the calendar attachment of the event for the attendee.
+// Every invite of the event has the same UID, so calendar clients update or
+// remove the entry they already have.
+func newInvite(method ical.Method, evt event.Event, location string, organizer ical.Person, attendee user.User) letter.Attachment {
+	return newCalendarInvite(method, []ical.Event{calendarEvent(evt, location, organizer)}, attendee)
+}
+
+// newCalendarInvite builds a calendar attachment with any number of events,
+// such as the occurrences of a recurring event.
+func newCalendarInvite(method ical.Method, vevents []ical.Event, attendee user.User) letter.Attachment {
+	for i := range vevents {
+		vevents[i].Attendees = []ical.Person{{
+			Name:  fmt.Sprintf("%s %s", attendee.Name, attendee.LastName),
+			Email: attendee.Email,
+		}}
+		vevents[i].Cancelled = vevents[i].Cancelled || method == ical.MethodCancel
+	}
+
+	cal := ical.Calendar{
+		Method: method,
+		Events: vevents,
+	}
+
+	return letter.Attachment{
+		Name:        inviteFileName,
+		ContentType: fmt.Sprintf("text/calendar; charset=utf-8; method=%s", method),
+		Data:        cal.Encode(),
+	}
+}
+
+// calendarEvent describes the event the same way in invites and feeds.
+func calendarEvent(evt event.Event, location string, organizer ical.Person) ical.Event {
+	vevent := ical.Event{
+		UID:       ical.UID(evt.ID),
+		Sequence:  evt.Sequence,
+		Start:     evt.Date,
+		End:       evt.EndsAt,
+		Summary:   evt.Title,
+		Location:  location,
+		Lat:       evt.Location.Lat(),
+		Lon:       evt.Location.Lon(),
+		HasGeo:    true,
+		Organizer: organizer,
+		Cancelled: evt.Status == event.StatusCancelled,
+	}
+
+	if evt.Description != nil {
+		vevent.Description = *evt.Description
+	}
+
+	return vevent
+}
+
+// zoneFor returns the zone the times of the event are written in for the
+// user: the user's own, or else the zone of the event.
+func zoneFor(usr user.User, evt event.Event) *time.Location {
+	if usr.TimeZone != nil {
+		return event.LoadZone(*usr.TimeZone)
+	}
+	return evt.Zone()
+}
+
+// organizerOf returns the creator of the event as the organizer of the
+// invites. Invites are still sent without one if the creator is gone.
+func (s *Service) organizerOf(ctx context.Context, evt event.Event) ical.Person {
+	creator, err := s.usrRepo.GetUserByID(ctx, evt.CreatorID)
+	if err != nil {
+		s.log.Error("error getting event creator",
+			slog.String("event_id", evt.ID.String()),
+			slog.String("err", err.Error()),
+		)
+		return ical.Person{}
+	}
+
+	return ical.Person{
+		Name:  fmt.Sprintf("%s %s", creator.Name, creator.LastName),
+		Email: creator.Email,
+	}
+}
+
+// notifyEnrolled emails the user a confirmation of the enrollment with an
+// invite. Waitlisted users get their invite once they are promoted.
+func (s *Service) notifyEnrolled(ctx context.Context, usr user.User, enrlmnt enrollment.Enrollment) {
+	const op = "service.notifyEnrolled"
+
+	if enrlmnt.Status != enrollment.StatusConfirmed {
+		return
+	}
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", enrlmnt.EventId.String()),
+		slog.String("user_id", usr.ID.String()),
+	)
+
+	evt, err := s.evtRepo.GetEvent(ctx, enrlmnt.EventId)
+	if err != nil {
+		log.Error("error getting event", slog.String("err", err.Error()))
+		return
+	}
+
+	location := s.describeLocation(ctx, evt.Location)
+
+	content, err := letter.NewEnrollmentConfirmation(letter.ParseLocale(usr.Locale), usr.Name, evt.Title, location, evt.Date.In(zoneFor(usr, evt)))
+	if err != nil {
+		log.Error("error rendering enrollment email", slog.String("err", err.Error()))
+		return
+	}
+
+	invite := newInvite(ical.MethodRequest, evt, location, s.organizerOf(ctx, evt), usr)
+
+	if err := s.sendMessage(ctx, content, usr.Email, invite); err != nil {
+		log.Error("error sending enrollment email", slog.String("err", err.Error()))
+	}
+}
+
+// notifyCancelled emails everyone enrolled in the deleted or cancelled event.
+// The invite removes the event from the calendars of the confirmed users, so
+// evt.Sequence must already be newer than the last invite.
+func (s *Service) notifyCancelled(ctx context.Context, evt event.Event, enrollments []enrollment.Enrollment) {
+	const op = "service.notifyCancelled"
+
+	if len(enrollments) == 0 {
+		return
+	}
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", evt.ID.String()),
+	)
+
+	organizer := s.organizerOf(ctx, evt)
+
+	var reason string
+	if evt.CancellationReason != nil {
+		reason = *evt.CancellationReason
+	}
+
+	msgs := make([]letter.Message, 0, len(enrollments))
+
+	for _, enrlmnt := range enrollments {
+		usr, err := s.usrRepo.GetUserByID(ctx, enrlmnt.UserID)
+		if err != nil {
+			log.Error("error getting user",
+				slog.String("user_id", enrlmnt.UserID.String()),
+				slog.String("err", err.Error()),
+			)
+			continue
+		}
+
+		content, err := letter.NewCancellationNotification(letter.ParseLocale(usr.Locale), usr.Name, evt.Title, reason, evt.Date.In(zoneFor(usr, evt)))
+		if err != nil {
+			log.Error("error rendering cancellation email", slog.String("err", err.Error()))
+			return
+		}
+
+		msg := letter.Message{
+			To:      usr.Email,
+			Content: content,
+		}
+
+		if enrlmnt.Status == enrollment.StatusConfirmed {
+			msg.Attachments = []letter.Attachment{newInvite(ical.MethodCancel, evt, "", organizer, usr)}
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	if err := s.enqueue(ctx, msgs...); err != nil {
+		log.Error("error sending cancellation emails", slog.String("err", err.Error()))
+	}
+}
@@ -8,159 +8,193 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/ical"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
 	"github.com/paulmach/orb"
-	"gopkg.in/gomail.v2"
 )
 
-const DateFormat = "January 2, 2006 15:04"
-
 type NominatimResponse struct {
 	DisplayName string `json:"display_name"`
 }
 
-func (s *Service) SendEventChangesNotificationEmail(
+// notifyEventChanges queues a letter about the changes of the event for every
+// enrolled user, in the user's language. Every user-visible field is
+// compared, nothing is sent when none of them changed.
+func (s *Service) notifyEventChanges(
 	ctx context.Context,
 	oldEvent event.Event,
 	newEvent event.Event,
-	name string,
-	email string,
+	enrollments []enrollment.Enrollment,
 ) error {
-	const op = "service.SendNotificationEmail"
+	const op = "service.notifyEventChanges"
+
 	log := s.log.With(
 		slog.String("op", op),
 		slog.String("event_id", oldEvent.ID.String()),
-		slog.String("email", email),
 	)
 
-	log.Info("attempt to send notification email")
+	diff := event.Diff(oldEvent, newEvent)
+
+	if len(diff) == 0 || len(enrollments) == 0 {
+		return nil
+	}
 
-	changes := make([]letter.Change, 0, 2)
+	// the new location goes into the invites even if it did not change
+	newLocation := s.describeLocation(ctx, newEvent.Location)
 
-	if !oldEvent.Date.Equal(newEvent.Date) {
-		changes = append(changes, letter.NewChange(
-			"Date",
-			oldEvent.Date.Format(DateFormat),
-			newEvent.Date.Format(DateFormat),
-		))
+	locations := map[orb.Point]string{newEvent.Location: newLocation}
+	for _, change := range diff {
+		if change.Field == event.FieldLocation {
+			locations[oldEvent.Location] = s.describeLocation(ctx, oldEvent.Location)
+		}
 	}
 
-	if !oldEvent.Location.Equal(newEvent.Location) {
-
-		var oldLocation, newLocation string
-		var err error
-
-		if s.cache != nil {
-			oldLocation, err = s.cache.GetLocation(ctx, oldEvent.Location)
-			if err != nil {
-				log.Error("error getting cache entry", slog.String("err", err.Error()))
-				if !errors.Is(err, storage.ErrorLocationNotFound) {
-					return fmt.Errorf("op: %s, err: %v", op, err)
-				}
-
-				oldLocation, err = s.ReverseGeocode(oldEvent.Location)
-				if err != nil {
-					return fmt.Errorf("op: %s, err: %v", op, err)
-				}
-
-				err = s.cache.SetLocation(ctx, oldEvent.Location, oldLocation)
-				if err != nil {
-					return fmt.Errorf("op: %s, err: %v", op, err)
-				}
-			}
-
-			newLocation, err = s.cache.GetLocation(ctx, newEvent.Location)
-			if err != nil {
-				log.Error("error getting cache entry", slog.String("err", err.Error()))
-				if !errors.Is(err, storage.ErrorLocationNotFound) {
-					return fmt.Errorf("op: %s, err: %v", op, err)
-				}
-
-				newLocation, err = s.ReverseGeocode(newEvent.Location)
-				if err != nil {
-					return fmt.Errorf("op: %s, err: %v", op, err)
-				}
-
-				err = s.cache.SetLocation(ctx, newEvent.Location, newLocation)
-				if err != nil {
-					return fmt.Errorf("op: %s, err: %v", op, err)
-				}
-			}
-		} else {
-
-			oldLocation, err = s.ReverseGeocode(oldEvent.Location)
-			if err != nil {
-				return fmt.Errorf("op: %s, err: %v", op, err)
-			}
-
-			newLocation, err = s.ReverseGeocode(newEvent.Location)
-			if err != nil {
-				return fmt.Errorf("op: %s, err: %v", op, err)
-			}
+	organizer := s.organizerOf(ctx, newEvent)
 
+	// values are formatted differently in every language and zone
+	changesIn := func(locale letter.Locale, zone *time.Location) []letter.Change {
+		changes := make([]letter.Change, 0, len(diff))
+
+		for _, change := range diff {
+			changes = append(changes, letter.NewChange(
+				change.Field,
+				formatValue(locale, zone, change.Old, locations),
+				formatValue(locale, zone, change.New, locations),
+			))
 		}
 
-		changes = append(changes, letter.NewChange(
-			"Location",
-			oldLocation,
-			newLocation,
-		))
+		return changes
 	}
 
-	if len(changes) <= 0 {
-		return nil
+	log.Info("notifying users of event changes",
+		slog.Int("count", len(enrollments)),
+		slog.Int("changes", len(diff)),
+	)
+
+	msgs := make([]letter.Message, 0, len(enrollments))
+
+	for _, enrlmnt := range enrollments {
+		usr, err := s.usrRepo.GetUserByID(ctx, enrlmnt.UserID)
+		if err != nil {
+			log.Error("error getting user",
+				slog.String("user_id", enrlmnt.UserID.String()),
+				slog.String("err", err.Error()),
+			)
+			continue
+		}
+
+		locale := letter.ParseLocale(usr.Locale)
+
+		content, err := letter.NewUpdateNotification(locale, usr.Name, oldEvent.Title, changesIn(locale, zoneFor(usr, newEvent)))
+		if err != nil {
+			return fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		msg := letter.Message{
+			To:      usr.Email,
+			Content: content,
+		}
+
+		if enrlmnt.Status == enrollment.StatusConfirmed {
+			msg.Attachments = []letter.Attachment{newInvite(ical.MethodRequest, newEvent, newLocation, organizer, usr)}
+		}
+
+		msgs = append(msgs, msg)
 	}
 
-	content := letter.NewUpdateNotification(name, oldEvent.Title, changes)
+	return s.enqueue(ctx, msgs...)
+}
 
-	return s.sendMessage("Changes in an upcoming event", content, email)
+// formatValue writes a value of event.FieldChange for a letter. Times are
+// written in the given zone.
+func formatValue(locale letter.Locale, zone *time.Location, value any, locations map[orb.Point]string) string {
+	switch v := value.(type) {
+	case time.Time:
+		return letter.FormatDate(locale, v.In(zone))
+	case orb.Point:
+		return locations[v]
+	case bool:
+		return letter.FormatBool(locale, v)
+	case []string:
+		if len(v) == 0 {
+			return "—"
+		}
+		return strings.Join(v, ", ")
+	case string:
+		if v == "" {
+			return "—"
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
 }
 
+// SendEventNotificationEmail queues a reminder of the event for the user,
+// with an invite in case it is not in the user's calendar yet.
 func (s *Service) SendEventNotificationEmail(
 	ctx context.Context,
-	name string,
-	email string,
+	usr user.User,
 	evt event.Event,
 ) error {
-	const op = "service.SendNotificationEmail"
+	location := s.describeLocation(ctx, evt.Location)
+
+	content, err := letter.NewReminderNotification(
+		letter.ParseLocale(usr.Locale),
+		usr.Name,
+		evt.Title,
+		location,
+		evt.Date.In(zoneFor(usr, evt)),
+	)
+	if err != nil {
+		return err
+	}
+
+	invite := newInvite(ical.MethodRequest, evt, location, s.organizerOf(ctx, evt), usr)
+
+	return s.sendMessage(ctx, content, usr.Email, invite)
+}
+
+// describeLocation returns a readable address of the point, from the cache
+// when possible. If the address cannot be found the coordinates are used, so
+// a letter is never lost because of the geocoder.
+func (s *Service) describeLocation(ctx context.Context, point orb.Point) string {
+	const op = "service.describeLocation"
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("email", email),
-		slog.String("event_id", evt.ID.String()),
 	)
 
-	location, err := s.cache.GetLocation(ctx, evt.Location)
-	if err != nil && !errors.Is(err, storage.ErrorLocationNotFound) {
-		log.Error("error accessing cache", slog.String("err", err.Error()))
-		return fmt.Errorf("op: %s, err: %v", op, err)
+	if s.cache != nil {
+		location, err := s.cache.GetLocation(ctx, point)
+		if err == nil {
+			return location
+		}
+		if !errors.Is(err, storage.ErrorLocationNotFound) {
+			log.Error("error accessing cache", slog.String("err", err.Error()))
+		}
 	}
 
+	location, err := s.ReverseGeocode(point)
 	if err != nil {
-		location, err = s.ReverseGeocode(evt.Location)
-		if err != nil {
-			log.Error("error getting locaion from nominatim", slog.String("err", err.Error()))
-			return fmt.Errorf("op: %s, err: %v", op, err)
-		}
+		log.Error("error getting location from nominatim", slog.String("err", err.Error()))
+		return fmt.Sprintf("%f, %f", point.Lat(), point.Lon())
+	}
 
-		err = s.cache.SetLocation(ctx, evt.Location, location)
-		if err != nil {
+	if s.cache != nil {
+		if err := s.cache.SetLocation(ctx, point, location); err != nil {
 			log.Error("error writing location to cache", slog.String("err", err.Error()))
-			// return fmt.Errorf("op: %s, err: %v", op, err)
 		}
 	}
 
-	content := letter.NewReminderNotification(
-		name,
-		evt.Title,
-		location,
-		evt.Date.Format(DateFormat),
-	)
-
-	return s.sendMessage("Upcoming event", content, email)
+	return location
 }
 
 func (s *Service) ReverseGeocode(point orb.Point) (string, error) {
@@ -199,27 +233,3 @@ func (s *Service) ReverseGeocode(point orb.Point) (string, error) {
 
 	return result.DisplayName, nil
 }
-
-func (s *Service) sendMessage(subject, content, email string) error {
-	const op = "service.sendMessage"
-
-	m := gomail.NewMessage()
-
-	m.SetHeader("From", s.smtp.Username)
-	m.SetHeader("To", email)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/html", content)
-
-	d := gomail.NewDialer(
-		s.smtp.Host,
-		s.smtp.Port,
-		s.smtp.Username,
-		s.smtp.Password,
-	)
-
-	if err := d.DialAndSend(m); err != nil {
-		return fmt.Errorf("op: %s, err: %v", op, err)
-	}
-
-	return nil
-}
@@ -3,20 +3,40 @@ package service
 import (
 	"context"
 	"log/slog"
-	"os"
 	"time"
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/notification"
+	"github.com/google/uuid"
 )
 
-type UserNotificationRequest struct {
-	Email string
-	Evt   event.Event
+type NotificationRepository interface {
+	ClaimNotifications(ctx context.Context, limit int, lease time.Duration, maxAttempts int) ([]notification.Scheduled, error)
+	MarkNotificationSent(ctx context.Context, id uuid.UUID) error
+	MarkNotificationFailed(ctx context.Context, id uuid.UUID, reason string, retryIn time.Duration) error
+	GetReminderOffsets(ctx context.Context, userID uuid.UUID) ([]int, error)
+	SetReminderOffsets(ctx context.Context, userID uuid.UUID, offsets []int) error
+	SetEnrollmentReminderOffsets(ctx context.Context, eventID, userID uuid.UUID, offsets []int) error
 }
 
-const monitoringCount = 4
+const (
+	monitoringCount = 4
+
+	// pollInterval is how often the job table is checked for due reminders.
+	pollInterval = 30 * time.Second
+	// notificationLease is how long a claimed reminder is hidden from other
+	// workers and replicas. It must be longer than sending one email takes.
+	notificationLease = 5 * time.Minute
+	// maxNotificationAttempts is how many times a reminder is tried before it
+	// is left in the table with its last error.
+	maxNotificationAttempts = 5
+)
 
-func (s *Service) Monitor(done <-chan os.Signal) {
+// Monitor sends the reminders stored in the scheduled_notifications table as
+// they become due. Reminders are claimed with row locks, so any number of
+// replicas can run Monitor at the same time, and a reminder that was not sent
+// before a restart is picked up again once its lease expires.
+func (s *Service) Monitor(done <-chan struct{}) {
 	const op = "service.Monitor"
 	log := s.log.With(
 		slog.String("op", op),
@@ -24,146 +44,90 @@ func (s *Service) Monitor(done <-chan os.Signal) {
 
 	log.Info("starting monitoring")
 
-	var lastChecked = time.Now()
-
-	userNotificationQueue := make(chan UserNotificationRequest, monitoringCount)
-	eventQueue := make(chan event.Event, monitoringCount)
-
-	defer close(eventQueue)
-	defer close(userNotificationQueue)
+	tasks := make(chan notification.Scheduled, monitoringCount)
+	defer close(tasks)
 
 	for range monitoringCount {
-		go s.notifyOfEventWorker(eventQueue, userNotificationQueue)
-		go s.notifyUserWorker(userNotificationQueue)
+		go s.notifyUserWorker(tasks)
 	}
 
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-done:
 			return
-		default:
-			diff := time.Since(lastChecked)
-			var sleepTime time.Duration
-
-			if diff < 20*time.Minute {
-				sleepTime = 405 * 3 * time.Second
-			} else {
-				sleepTime = 395 * 3 * time.Second
-			}
-
-			lastChecked = time.Now()
-
-			before := lastChecked.Add((24*60 + 10) * time.Minute)
-			after := lastChecked.Add((24*60 - 10) * time.Minute)
-
-			eventsToNotifyOf, err := s.GetAllEvents(
+		case <-ticker.C:
+			due, err := s.ntfRepo.ClaimNotifications(
 				context.Background(),
-				nil,
-				nil,
-				nil,
-				nil,
-				&before,
-				&after,
-				nil,
-				nil,
-				nil,
+				monitoringCount*4,
+				notificationLease,
+				maxNotificationAttempts,
 			)
-
-			log.Debug(
-				"some debug message",
-				slog.Time("now", time.Now()),
-				slog.Any("events", eventsToNotifyOf),
-				slog.Time("before", before),
-				slog.Time("after", after),
-				slog.Time("lastChecked", lastChecked),
-			)
-
 			if err != nil {
-				log.Error(
-					"error getting events to notify of",
-					slog.String("err", err.Error()),
-				)
-				time.Sleep(sleepTime)
+				log.Error("error claiming notifications", slog.String("err", err.Error()))
 				continue
 			}
 
-			for _, event := range eventsToNotifyOf {
-				eventQueue <- event
-			}
+			log.Debug("claimed notifications", slog.Int("count", len(due)))
 
-			time.Sleep(sleepTime)
+			for _, task := range due {
+				tasks <- task
+			}
 		}
-
 	}
 }
 
-func (s *Service) notifyOfEventWorker(eventQueue <-chan event.Event, userNotificationQueue chan<- UserNotificationRequest) {
-	const op = "service.NotifyOfEvent"
-
-	log := s.log.With(
-		slog.String("op", op),
-	)
+func (s *Service) notifyUserWorker(tasks <-chan notification.Scheduled) {
+	const op = "service.notifyUserWorker"
 
-	for evt := range eventQueue {
-		log.Info(
-			"notifying of event",
-			slog.String("event_id", evt.ID.String()),
-		)
-		usersToNotifyOfEvent, err := s.evtRepo.GetAllSubscriptions(
-			context.Background(),
-			evt.ID,
+	for task := range tasks {
+		log := s.log.With(
+			slog.String("op", op),
+			slog.String("notification_id", task.ID.String()),
+			slog.String("user_id", task.UserID.String()),
+			slog.String("event_id", task.EventID.String()),
+			slog.Int("attempt", task.Attempts),
 		)
 
-		if err != nil {
-			log.Error("error retrieving enrolled users", slog.String("err", err.Error()))
+		log.Info("notifying user of event")
+
+		if err := s.sendReminder(task); err != nil {
+			log.Error("error notifying user of event", slog.String("err", err.Error()))
+
+			retryIn := time.Duration(task.Attempts*task.Attempts) * time.Minute
+			if err := s.ntfRepo.MarkNotificationFailed(context.Background(), task.ID, err.Error(), retryIn); err != nil {
+				log.Error("error recording failed notification", slog.String("err", err.Error()))
+			}
+
 			continue
 		}
 
-		for _, user := range usersToNotifyOfEvent {
-			userNotificationQueue <- UserNotificationRequest{
-				Email: user.UserEmail,
-				Evt:   evt,
-			}
+		if err := s.ntfRepo.MarkNotificationSent(context.Background(), task.ID); err != nil {
+			log.Error("error recording sent notification", slog.String("err", err.Error()))
 		}
 	}
-
 }
 
-func (s *Service) notifyUserWorker(taskQueue <-chan UserNotificationRequest) {
-	const op = "service.notifyUserWorker"
+func (s *Service) sendReminder(task notification.Scheduled) error {
+	ctx := context.Background()
 
-	log := s.log.With(
-		slog.String("op", op),
-	)
-
-	for task := range taskQueue {
-		log.Info(
-			"notifying user of event",
-			slog.String("emain", task.Email),
-			slog.String("event_id", task.Evt.ID.String()),
-		)
-		usr, err := s.usrRepo.GetUser(context.Background(), task.Email)
-		if err != nil {
-			log.Error("error getting user",
-				slog.String("email", task.Email),
-				slog.String("err", err.Error()),
-			)
-
-			continue
-		}
+	usr, err := s.usrRepo.GetUserByID(ctx, task.UserID)
+	if err != nil {
+		return err
+	}
 
-		err = s.SendEventNotificationEmail(
-			context.Background(),
-			usr.Name,
-			usr.Email,
-			task.Evt,
-		)
+	evt, err := s.evtRepo.GetEvent(ctx, task.EventID)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			log.Error("error notifying user of event",
-				slog.String("email", task.Email),
-				slog.String("err", err.Error()),
-			)
-		}
+	// reminders are dropped on cancellation, this covers one claimed just
+	// before it
+	if evt.Status == event.StatusCancelled {
+		return nil
 	}
+
+	return s.SendEventNotificationEmail(ctx, usr, evt)
 }
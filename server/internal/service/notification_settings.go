@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	maxReminderOffsets = 5
+	// maxReminderOffset is the longest lead time of a reminder, 30 days.
+	maxReminderOffset = 30 * 24 * 60
+)
+
+func (s *Service) GetNotificationSettings(ctx context.Context, userID uuid.UUID) (user.NotificationSettings, error) {
+	const op = "service.GetNotificationSettings"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	offsets, err := s.ntfRepo.GetReminderOffsets(ctx, userID)
+	if err != nil {
+		log.Error("error getting reminder offsets", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNoUser) {
+			return user.NotificationSettings{}, ErrUserNotFound
+		}
+		return user.NotificationSettings{}, fmt.Errorf("error getting notification settings")
+	}
+
+	return user.NotificationSettings{ReminderOffsets: offsets}, nil
+}
+
+// UpdateNotificationSettings replaces the reminder offsets of the user. The
+// pending reminders of upcoming events follow the new offsets, reminders that
+// were already sent are not repeated.
+func (s *Service) UpdateNotificationSettings(ctx context.Context, userID uuid.UUID, settings user.NotificationSettings) (user.NotificationSettings, error) {
+	const op = "service.UpdateNotificationSettings"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	offsets, err := normalizeOffsets(settings.ReminderOffsets)
+	if err != nil {
+		log.Error("invalid reminder offsets", slog.Any("offsets", settings.ReminderOffsets))
+		return user.NotificationSettings{}, err
+	}
+
+	if err := s.ntfRepo.SetReminderOffsets(ctx, userID, offsets); err != nil {
+		log.Error("error setting reminder offsets", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNoUser) {
+			return user.NotificationSettings{}, ErrUserNotFound
+		}
+		return user.NotificationSettings{}, fmt.Errorf("error updating notification settings")
+	}
+
+	return user.NotificationSettings{ReminderOffsets: offsets}, nil
+}
+
+// SetEnrollmentReminders overrides the reminder offsets of the user for one
+// event. A nil offsets makes the event follow the settings of the user again.
+func (s *Service) SetEnrollmentReminders(ctx context.Context, eventID, userID uuid.UUID, offsets []int) ([]int, error) {
+	const op = "service.SetEnrollmentReminders"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	if offsets != nil {
+		normalized, err := normalizeOffsets(offsets)
+		if err != nil {
+			log.Error("invalid reminder offsets", slog.Any("offsets", offsets))
+			return nil, err
+		}
+		offsets = normalized
+	}
+
+	if err := s.ntfRepo.SetEnrollmentReminderOffsets(ctx, eventID, userID, offsets); err != nil {
+		log.Error("error setting reminder offsets", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNotEnrolled) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, fmt.Errorf("error updating reminders")
+	}
+
+	return offsets, nil
+}
+
+// normalizeOffsets validates the offsets and returns them deduplicated, the
+// earliest reminder first.
+func normalizeOffsets(offsets []int) ([]int, error) {
+	result := make([]int, 0, len(offsets))
+
+	for _, offset := range offsets {
+		if offset < 1 || offset > maxReminderOffset {
+			return nil, ErrInvalidOffsets
+		}
+		if !slices.Contains(result, offset) {
+			result = append(result, offset)
+		}
+	}
+
+	if len(result) > maxReminderOffsets {
+		return nil, ErrInvalidOffsets
+	}
+
+	slices.Sort(result)
+	slices.Reverse(result)
+
+	return result, nil
+}
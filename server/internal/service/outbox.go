@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+)
+
+type MailRepository interface {
+	EnqueueEmails(ctx context.Context, msgs []letter.Message) error
+	ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]letter.Outbound, error)
+	MarkEmailSent(ctx context.Context, id uuid.UUID) error
+	RetryEmail(ctx context.Context, id uuid.UUID, reason string, retryIn time.Duration) error
+	DeadLetterEmail(ctx context.Context, id uuid.UUID, reason string) error
+	GetDeadLetters(ctx context.Context, limit, offset int) ([]letter.DeadLetter, error)
+	RedriveDeadLetter(ctx context.Context, id uuid.UUID) error
+}
+
+const (
+	deliveryWorkers      = 4
+	deliveryPollInterval = 5 * time.Second
+	// deliveryLease must be longer than sending one email takes.
+	deliveryLease = 2 * time.Minute
+	// maxDeliveryAttempts is how many times an email is tried before it is
+	// moved to the dead-letter table.
+	maxDeliveryAttempts = 8
+	// The delay before the next attempt doubles from retryBaseDelay up to
+	// retryMaxDelay, less the jitter of retryDelay.
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 2 * time.Hour
+
+	maxDeadLettersPageSize = 100
+)
+
+// sendMessage puts the email into the outbound queue. It is delivered by
+// DeliverEmails, with retries, even if the app restarts in between.
+func (s *Service) sendMessage(ctx context.Context, content letter.Content, email string, attachments ...letter.Attachment) error {
+	return s.enqueue(ctx, letter.Message{
+		To:          email,
+		Content:     content,
+		Attachments: attachments,
+	})
+}
+
+func (s *Service) enqueue(ctx context.Context, msgs ...letter.Message) error {
+	const op = "service.enqueue"
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := s.mailRepo.EnqueueEmails(ctx, msgs); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeliverEmails sends the queued emails until done is closed. Emails are
+// claimed with row locks, so any number of replicas can run it at once.
+// Delivery is at least once: an email is marked sent only after the server
+// accepted it, so a crash in between sends it again once its lease expires.
+// Claimed emails that were not handed to a worker before done was closed are
+// picked up the same way.
+func (s *Service) DeliverEmails(done <-chan struct{}) {
+	const op = "service.DeliverEmails"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("starting email delivery")
+
+	tasks := make(chan letter.Outbound, deliveryWorkers)
+	defer close(tasks)
+
+	for range deliveryWorkers {
+		go s.deliveryWorker(tasks)
+	}
+
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			emails, err := s.mailRepo.ClaimEmails(context.Background(), deliveryWorkers*4, deliveryLease)
+			if err != nil {
+				log.Error("error claiming emails", slog.String("err", err.Error()))
+				continue
+			}
+
+			for _, email := range emails {
+				select {
+				case tasks <- email:
+				case <-done:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Service) deliveryWorker(tasks <-chan letter.Outbound) {
+	const op = "service.deliveryWorker"
+
+	for task := range tasks {
+		log := s.log.With(
+			slog.String("op", op),
+			slog.String("email_id", task.ID.String()),
+			slog.Int("attempt", task.Attempts),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryLease)
+		err := s.mailer.Send(ctx, task.Message)
+		cancel()
+
+		if err == nil {
+			if err := s.mailRepo.MarkEmailSent(context.Background(), task.ID); err != nil {
+				log.Error("error recording sent email", slog.String("err", err.Error()))
+			}
+			continue
+		}
+
+		log.Error("error sending email", slog.String("err", err.Error()))
+
+		if task.Attempts >= maxDeliveryAttempts {
+			log.Error("giving up on email, moving it to dead letters")
+			if err := s.mailRepo.DeadLetterEmail(context.Background(), task.ID, err.Error()); err != nil {
+				log.Error("error moving email to dead letters", slog.String("err", err.Error()))
+			}
+			continue
+		}
+
+		if err := s.mailRepo.RetryEmail(context.Background(), task.ID, err.Error(), retryDelay(task.Attempts)); err != nil {
+			log.Error("error scheduling email retry", slog.String("err", err.Error()))
+		}
+	}
+}
+
+// retryDelay is the exponential backoff after the given number of attempts.
+// Up to half of the delay is taken off at random, so the emails that failed
+// together, say while the mail server was down, are not retried together.
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, retryMaxDelay)
+	return delay - rand.N(delay/2+1)
+}
+
+func (s *Service) ListFailedEmails(ctx context.Context, limit, offset int) ([]letter.DeadLetter, error) {
+	const op = "service.ListFailedEmails"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	if limit <= 0 || limit > maxDeadLettersPageSize {
+		limit = maxDeadLettersPageSize
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	letters, err := s.mailRepo.GetDeadLetters(ctx, limit, offset)
+	if err != nil {
+		log.Error("error retrieving failed emails", slog.String("err", err.Error()))
+		return nil, fmt.Errorf("error retrieving failed emails")
+	}
+
+	return letters, nil
+}
+
+// RetryFailedEmail puts a dead letter back into the queue.
+func (s *Service) RetryFailedEmail(ctx context.Context, id uuid.UUID) error {
+	const op = "service.RetryFailedEmail"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("email_id", id.String()),
+	)
+
+	if err := s.mailRepo.RedriveDeadLetter(ctx, id); err != nil {
+		log.Error("error re-driving email", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorEmailNotFound) {
+			return ErrEmailNotFound
+		}
+		return fmt.Errorf("error re-driving email")
+	}
+
+	log.Info("failed email queued again")
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		max      time.Duration
+	}{
+		{attempts: 0, max: retryBaseDelay},
+		{attempts: 1, max: retryBaseDelay},
+		{attempts: 2, max: 2 * retryBaseDelay},
+		{attempts: 3, max: 4 * retryBaseDelay},
+		{attempts: 5, max: 16 * retryBaseDelay},
+		{attempts: 8, max: 128 * retryBaseDelay},
+		{attempts: 9, max: retryMaxDelay},
+		{attempts: 20, max: retryMaxDelay},
+		{attempts: 1000, max: retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		lowest, highest := tt.max, time.Duration(0)
+
+		for range 1000 {
+			got := retryDelay(tt.attempts)
+			if got < tt.max/2 || got > tt.max {
+				t.Fatalf("retryDelay(%d) = %v, want between %v and %v", tt.attempts, got, tt.max/2, tt.max)
+			}
+			lowest = min(lowest, got)
+			highest = max(highest, got)
+		}
+
+		if lowest == highest {
+			t.Errorf("retryDelay(%d) is always %v, want jitter", tt.attempts, lowest)
+		}
+	}
+}
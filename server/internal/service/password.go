@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+)
+
+const (
+	passwordResetLimit  = 3
+	passwordResetWindow = time.Hour
+
+	passwordResetPollInterval = 5 * time.Second
+	passwordResetClaimLimit   = 32
+	// passwordResetLease must be longer than handling one request takes.
+	passwordResetLease = time.Minute
+)
+
+// ForgotPassword queues a request to mail a single-use reset token to the
+// user. The address is only looked up by SendPasswordResets, so the response,
+// its timing and its errors are the same for registered, unknown and rate
+// limited addresses alike.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	const op = "service.ForgotPassword"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	if err := s.usrRepo.QueuePasswordResetRequest(ctx, email); err != nil {
+		log.Error("error queueing password reset request", slog.String("err", err.Error()))
+		return fmt.Errorf("error requesting password reset")
+	}
+
+	return nil
+}
+
+// SendPasswordResets handles the queued reset requests until done is closed.
+// Any number of replicas can run it. A request is only taken off the queue
+// once its letter is queued, so a failed one is tried again after its lease;
+// a crash right in between may send the letter twice.
+func (s *Service) SendPasswordResets(done <-chan struct{}) {
+	const op = "service.SendPasswordResets"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("starting password reset delivery")
+
+	ticker := time.NewTicker(passwordResetPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+
+			requests, err := s.usrRepo.ClaimPasswordResetRequests(ctx, passwordResetClaimLimit, passwordResetLease)
+			if err != nil {
+				log.Error("error claiming password reset requests", slog.String("err", err.Error()))
+				continue
+			}
+
+			for _, req := range requests {
+				if err := s.sendPasswordReset(ctx, req.Email); err != nil {
+					log.Error("error sending password reset", slog.String("err", err.Error()))
+					continue
+				}
+
+				if err := s.usrRepo.DeletePasswordResetRequest(ctx, req.ID); err != nil {
+					log.Error("error removing handled password reset request", slog.String("err", err.Error()))
+				}
+			}
+		}
+	}
+}
+
+// sendPasswordReset mails a reset token to the owner of the address, unless
+// there is none or it has asked for too many already.
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
+	const op = "service.sendPasswordReset"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	usr, err := s.usrRepo.GetUser(ctx, email)
+	if err != nil {
+		log.Warn("password reset requested for unknown user", slog.String("err", err.Error()))
+		return nil
+	}
+
+	count, err := s.usrRepo.CountPasswordResets(ctx, usr.ID, time.Now().Add(-passwordResetWindow))
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if count >= passwordResetLimit {
+		log.Warn("password reset rate limit exceeded", slog.Int("count", count))
+		return nil
+	}
+
+	token, err := generateSecretToken()
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	err = s.usrRepo.InsertPasswordReset(ctx, usr.ID, hashToken(token), time.Now().Add(s.tokens.ResetTTL))
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	link := fmt.Sprintf("%s/password/reset?%s", s.urls.Client, url.Values{"token": {token}}.Encode())
+
+	content, err := letter.NewPasswordResetLetter(letter.ParseLocale(usr.Locale), usr.Name, link, token, s.tokens.ResetTTL)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err := s.sendMessage(ctx, content, usr.Email); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword and
+// signs the user out everywhere.
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	const op = "service.ResetPassword"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Error("error hashing password", slog.String("err", err.Error()))
+		return fmt.Errorf("invalid password")
+	}
+
+	userID, err := s.usrRepo.ResetPassword(ctx, hashToken(token), hash)
+	if err != nil {
+		log.Error("error resetting password", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorResetNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("error resetting password")
+	}
+
+	log.Info("password reset", slog.String("user_id", userID.String()))
+
+	return nil
+}
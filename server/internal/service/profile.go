@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+)
+
+func (s *Service) GetProfile(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	const op = "service.GetProfile"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error("error getting user", slog.String("err", err.Error()))
+		return user.User{}, ErrUserNotFound
+	}
+
+	usr.Password = ""
+
+	return usr, nil
+}
+
+// UpdateProfile changes the fields that are not nil and keeps the rest. The
+// locale selects the language of the letters sent to the user and the time
+// zone the times in them, an empty zone falls back to the zone of the event.
+func (s *Service) UpdateProfile(ctx context.Context, userID uuid.UUID, name, lastName, locale, timeZone *string) (user.User, error) {
+	const op = "service.UpdateProfile"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	usr, err := s.usrRepo.UpdateUser(ctx, userID, name, lastName, locale, timeZone)
+	if err != nil {
+		log.Error("error updating user", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNoUser) {
+			return user.User{}, ErrUserNotFound
+		}
+		return user.User{}, fmt.Errorf("error updating user")
+	}
+
+	return usr, nil
+}
+
+// ChangePassword replaces the password after checking the current one. Every
+// other session of the user is revoked, the one making the request stays.
+func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID, oldPassword, newPassword string) error {
+	const op = "service.ChangePassword"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error("error getting user", slog.String("err", err.Error()))
+		return ErrUserNotFound
+	}
+
+	if !checkPasswordHash(oldPassword, usr.Password) {
+		log.Error("incorrect password")
+		return ErrWrongPassword
+	}
+
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		log.Error("error hashing password", slog.String("err", err.Error()))
+		return fmt.Errorf("invalid password")
+	}
+
+	if err := s.usrRepo.UpdatePassword(ctx, userID, hash); err != nil {
+		log.Error("error updating password", slog.String("err", err.Error()))
+		return fmt.Errorf("error changing password")
+	}
+
+	if err := s.sesRepo.RevokeOtherSessions(ctx, userID, sessionID); err != nil {
+		log.Error("error revoking sessions", slog.String("err", err.Error()))
+		return fmt.Errorf("error changing password")
+	}
+
+	return nil
+}
+
+// DeleteAccount removes the user after checking the password. Events created
+// by the user are cancelled, so the enrolled users are told, and then deleted
+// with all their enrollments. The seats the user held on other events become
+// available again.
+func (s *Service) DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error {
+	const op = "service.DeleteAccount"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error("error getting user", slog.String("err", err.Error()))
+		return ErrUserNotFound
+	}
+
+	if !checkPasswordHash(password, usr.Password) {
+		log.Error("incorrect password")
+		return ErrWrongPassword
+	}
+
+	if err := s.cancelCreatedEvents(ctx, userID); err != nil {
+		log.Error("error cancelling created events", slog.String("err", err.Error()))
+		return fmt.Errorf("error deleting user")
+	}
+
+	promoted, err := s.usrRepo.DeleteUser(ctx, userID)
+	if err != nil {
+		log.Error("error deleting user", slog.String("err", err.Error()))
+		return fmt.Errorf("error deleting user")
+	}
+
+	s.notifyPromoted(ctx, promoted)
+
+	log.Info("account deleted")
+
+	return nil
+}
+
+// cancelCreatedEvents cancels the events of the user that have not ended
+// yet, the way CancelEvent does, before the account and the events go away.
+func (s *Service) cancelCreatedEvents(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+
+	page, err := s.evtRepo.GetAllEvents(ctx, event.Filter{CreatorID: &userID, From: &now, Sort: event.SortDate})
+	if err != nil {
+		return err
+	}
+
+	for _, evt := range page.Events {
+		if evt.Status == event.StatusCancelled {
+			continue
+		}
+
+		_, err := s.CancelEvent(ctx, userID, user.RoleUser, evt.ID, nil)
+		if err != nil && !errors.Is(err, ErrEventCancelled) && !errors.Is(err, ErrEventNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
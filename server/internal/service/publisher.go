@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const publishPollInterval = 30 * time.Second
+
+// PublishScheduledEvents publishes the drafts whose publish_at has come,
+// until done is closed. Any number of replicas can run it.
+func (s *Service) PublishScheduledEvents(done <-chan struct{}) {
+	const op = "service.PublishScheduledEvents"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("starting scheduled publishing")
+
+	ticker := time.NewTicker(publishPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ids, err := s.evtRepo.PublishDueEvents(context.Background())
+			if err != nil {
+				log.Error("error publishing scheduled events", slog.String("err", err.Error()))
+				continue
+			}
+
+			for _, id := range ids {
+				log.Info("scheduled event published", slog.String("event_id", id.String()))
+			}
+		}
+	}
+}
@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/ical"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/rrule"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+)
+
+// Occurrences of a series are created a year ahead, the rest are added as
+// time goes by. A series is extended once a day at most.
+const (
+	seriesHorizon        = 365 * 24 * time.Hour
+	seriesRefresh        = 24 * time.Hour
+	seriesMaxOccurrences = 500
+	seriesPollInterval   = time.Hour
+	seriesClaimLimit     = 64
+)
+
+// CreateSeries stores a recurring event on behalf of the authenticated user.
+// evt describes every occurrence and its date is the first start of the
+// rule. The starts in exdates are skipped. The first occurrence is returned.
+func (s *Service) CreateSeries(ctx context.Context, creatorID uuid.UUID, evt event.Event, rule string, exdates []time.Time) (event.Event, error) {
+	const op = "service.CreateSeries"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("creator_id", creatorID.String()),
+		slog.String("rrule", rule),
+	)
+
+	r, err := rrule.Parse(rule)
+	if err != nil {
+		log.Error("invalid recurrence rule", slog.String("error", err.Error()))
+		return event.Event{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	// the rule repeats the wall clock time in the zone of the event, across
+	// daylight saving changes
+	setEventTimes(&evt)
+	evt.Date = evt.Date.In(evt.Zone())
+	evt.Tags = event.NormalizeTags(evt.Tags)
+
+	if len(r.Between(evt.Date, nil, evt.Date.Add(-time.Nanosecond), evt.Date, 1)) == 0 {
+		log.Error("event date is not a start of the rule")
+		return event.Event{}, fmt.Errorf("%w: the date of the event must be the first start of the rule", ErrInvalidRule)
+	}
+
+	creator, err := s.usrRepo.GetUserByID(ctx, creatorID)
+	if err != nil {
+		log.Error("creator not found", slog.String("error", err.Error()))
+		return event.Event{}, ErrUserNotFound
+	}
+
+	evt.CreatorID = creator.ID
+
+	if err := s.requireVerified(creator); err != nil {
+		log.Error("creator has not verified email")
+		return event.Event{}, err
+	}
+
+	if err := s.requireEventType(ctx, evt.Type); err != nil {
+		log.Error("error checking event type", slog.String("error", err.Error()))
+		return event.Event{}, err
+	}
+
+	if evt.Status != event.StatusDraft {
+		now := time.Now()
+		evt.Status = event.StatusPublished
+		evt.PublishAt = &now
+	}
+
+	until := time.Now().Add(seriesHorizon)
+	if until.Before(evt.Date) {
+		until = evt.Date
+	}
+
+	starts := r.Between(evt.Date, exdates, evt.Date.Add(-time.Nanosecond), until, seriesMaxOccurrences)
+	if len(starts) == 0 {
+		log.Error("every start of the rule is excluded")
+		return event.Event{}, fmt.Errorf("%w: every start of the rule is excluded", ErrInvalidRule)
+	}
+
+	if len(starts) == seriesMaxOccurrences {
+		until = starts[len(starts)-1]
+	}
+
+	_, more := r.After(evt.Date, until)
+
+	series := event.Series{
+		RRule:          r.String(),
+		Start:          evt.Date,
+		ExDates:        exdates,
+		Template:       evt,
+		GeneratedUntil: until,
+		Finished:       !more,
+	}
+
+	occurrences, err := s.evtRepo.InsertSeries(ctx, &series, starts)
+	if err != nil || len(occurrences) == 0 {
+		log.Error("error inserting series", slog.Any("error", err))
+		return event.Event{}, fmt.Errorf("error creating event")
+	}
+
+	log.Info("series created",
+		slog.String("series_id", series.ID.String()),
+		slog.Int("occurrences", len(occurrences)),
+	)
+
+	return occurrences[0], nil
+}
+
+// ExtendSeries creates the occurrences of unbounded and long series as they
+// come within the horizon, until done is closed. Any number of replicas can
+// run it.
+func (s *Service) ExtendSeries(done <-chan struct{}) {
+	const op = "service.ExtendSeries"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("starting series extension")
+
+	ticker := time.NewTicker(seriesPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.extendSeries(context.Background(), log)
+		}
+	}
+}
+
+func (s *Service) extendSeries(ctx context.Context, log *slog.Logger) {
+	horizon := time.Now().Add(seriesHorizon)
+
+	series, err := s.evtRepo.GetSeriesToExtend(ctx, horizon.Add(-seriesRefresh), seriesClaimLimit)
+	if err != nil {
+		log.Error("error getting series to extend", slog.String("err", err.Error()))
+		return
+	}
+
+	for _, sr := range series {
+		log := log.With(slog.String("series_id", sr.ID.String()))
+
+		r, err := rrule.Parse(sr.RRule)
+		if err != nil {
+			log.Error("invalid stored recurrence rule", slog.String("err", err.Error()))
+			continue
+		}
+
+		until := horizon
+		start := sr.Start.In(sr.Template.Zone())
+
+		starts := r.Between(start, sr.ExDates, sr.GeneratedUntil, until, seriesMaxOccurrences)
+		if len(starts) == seriesMaxOccurrences {
+			until = starts[len(starts)-1]
+		}
+
+		_, more := r.After(start, until)
+
+		if err := s.evtRepo.ExtendSeries(ctx, sr, starts, until, !more); err != nil {
+			log.Error("error extending series", slog.String("err", err.Error()))
+			continue
+		}
+
+		log.Info("series extended", slog.Int("occurrences", len(starts)))
+	}
+}
+
+// SubscribeToSeries enrolls the user in every open occurrence of the
+// recurring event and in the ones to come. A single confirmation covers all
+// of them, with an invite for the occurrences that had seats.
+func (s *Service) SubscribeToSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) ([]enrollment.Enrollment, error) {
+	const op = "service.SubscribeToSeries"
+
+	log := s.log.With(
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
+		slog.String("op", op),
+	)
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error("error retrieving user", slog.String("err", err.Error()))
+		return nil, fmt.Errorf("error retrieving user")
+	}
+
+	if err := s.requireVerified(usr); err != nil {
+		log.Error("user has not verified email")
+		return nil, err
+	}
+
+	enrollments, err := s.evtRepo.SubscribeToSeries(ctx, eventID, userID)
+	if err != nil {
+		log.Error("error enrolling in series", slog.String("err", err.Error()))
+		switch {
+		default:
+			return nil, fmt.Errorf("error making an appointment")
+		case errors.Is(err, storage.ErrorEventNotFound):
+			return nil, ErrEventNotFound
+		case errors.Is(err, storage.ErrorNotRecurring):
+			return nil, ErrNotRecurring
+		case errors.Is(err, storage.ErrorAlreadyEnrolled):
+			return nil, ErrAlreadyEnrolled
+		}
+	}
+
+	s.notifySeriesEnrolled(ctx, usr, enrollments)
+
+	return enrollments, nil
+}
+
+// UnsubscribeFromSeries withdraws the user from the occurrences of the
+// recurring event that have not started and from the ones to come. Freed
+// seats go to the waitlists.
+func (s *Service) UnsubscribeFromSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) error {
+	const op = "service.UnsubscribeFromSeries"
+
+	log := s.log.With(
+		slog.String("user_id", userID.String()),
+		slog.String("event_id", eventID.String()),
+		slog.String("op", op),
+	)
+
+	promoted, err := s.evtRepo.UnsubscribeFromSeries(ctx, eventID, userID)
+	if err != nil {
+		log.Error("error withdrawing from series", slog.String("err", err.Error()))
+		switch {
+		default:
+			return fmt.Errorf("error deleting enrollment")
+		case errors.Is(err, storage.ErrorEventNotFound):
+			return ErrEventNotFound
+		case errors.Is(err, storage.ErrorNotRecurring):
+			return ErrNotRecurring
+		case errors.Is(err, storage.ErrorNotEnrolled):
+			return ErrNotEnrolled
+		}
+	}
+
+	s.notifyPromoted(ctx, promoted)
+
+	return nil
+}
+
+// UpdateFollowing applies the edit to the occurrence evt and every later
+// occurrence of its series, which move in time as much as evt does. Editing
+// the first occurrence changes the whole series, editing a later one splits
+// it in two. The days of rules with BYDAY, BYMONTHDAY or BYMONTH are kept,
+// so such occurrences can only be moved within their day. Unless the edit is
+// silent, the change of every occurrence is queued the way UpdateEvent does,
+// so it is batched with the other edits of that occurrence.
+func (s *Service) UpdateFollowing(ctx context.Context, userID uuid.UUID, role user.Role, evt event.Event, notify bool) (event.Event, error) {
+	const op = "service.UpdateFollowing"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+		slog.String("event_id", evt.ID.String()),
+		slog.Bool("notify", notify),
+	)
+
+	oldEvent, err := s.evtRepo.GetEvent(ctx, evt.ID)
+	if err != nil {
+		log.Error("error getting event", slog.String("error", err.Error()))
+		return event.Event{}, ErrEventNotFound
+	}
+
+	if oldEvent.CreatorID != userID && role != user.RoleAdmin {
+		log.Error("user is not the creator of the event")
+		return event.Event{}, ErrForbidden
+	}
+
+	if oldEvent.SeriesID == nil || oldEvent.RecurrenceID == nil {
+		log.Error("event is not recurring")
+		return event.Event{}, ErrNotRecurring
+	}
+
+	if oldEvent.Status == event.StatusCancelled {
+		log.Error("event is cancelled")
+		return event.Event{}, ErrEventCancelled
+	}
+
+	if evt.PublishAt != nil && oldEvent.Status != event.StatusDraft {
+		log.Error("only drafts can be scheduled")
+		return event.Event{}, ErrNotDraft
+	}
+
+	series, err := s.evtRepo.GetSeries(ctx, *oldEvent.SeriesID)
+	if err != nil {
+		log.Error("error getting series", slog.String("error", err.Error()))
+		return event.Event{}, ErrEventNotFound
+	}
+
+	r, err := rrule.Parse(series.RRule)
+	if err != nil {
+		log.Error("invalid stored recurrence rule", slog.String("error", err.Error()))
+		return event.Event{}, fmt.Errorf("error updating event")
+	}
+
+	if err := s.requireEventType(ctx, evt.Type); err != nil {
+		log.Error("error checking event type", slog.String("error", err.Error()))
+		return event.Event{}, err
+	}
+
+	keepEventTimes(&evt, oldEvent)
+	keepEventTags(&evt, oldEvent)
+
+	zone := event.LoadZone(evt.TimeZone)
+	start := series.Start.In(zone)
+	shift := evt.Date.Sub(oldEvent.Date)
+	from := oldEvent.RecurrenceID.In(zone)
+	moved := from.Add(shift)
+
+	if (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 || len(r.ByMonth) > 0) && !sameDay(from, moved) {
+		log.Error("occurrences of the rule cannot move to another day")
+		return event.Event{}, fmt.Errorf("%w: occurrences of a rule with BYDAY, BYMONTHDAY or BYMONTH can only be moved within their day", ErrInvalidRule)
+	}
+
+	evt.CreatorID = oldEvent.CreatorID
+	evt.SeriesID = oldEvent.SeriesID
+	evt.RecurrenceID = oldEvent.RecurrenceID
+
+	template := evt
+	template.ID = uuid.Nil
+	template.AvailableSeats = template.TotalSeats
+	template.SeriesID = nil
+	template.RecurrenceID = nil
+	template.RRule = nil
+	template.Status = series.Template.Status
+	if template.Status != event.StatusDraft {
+		template.PublishAt = series.Template.PublishAt
+	}
+
+	tailRule := r
+	var headRule *string
+
+	if from.After(start) {
+		head := r
+		if r.Count > 0 {
+			head.Count = r.CountBefore(start, from)
+			tailRule.Count = r.Count - head.Count
+		} else {
+			head.Until = from.Add(-time.Second)
+		}
+		rule := head.String()
+		headRule = &rule
+	}
+
+	if !tailRule.Until.IsZero() {
+		tailRule.Until = tailRule.Until.Add(shift)
+	}
+
+	var exdates []time.Time
+	for _, exdate := range series.ExDates {
+		if !exdate.Before(from) {
+			exdates = append(exdates, exdate.Add(shift))
+		}
+	}
+
+	tail := event.Series{
+		RRule:    tailRule.String(),
+		Start:    moved,
+		ExDates:  exdates,
+		Template: template,
+	}
+
+	revisions, promoted, err := s.evtRepo.UpdateFollowing(ctx, evt, shift, tail, headRule)
+	if err != nil {
+		log.Error("could not update occurrences", slog.String("error", err.Error()))
+		switch {
+		default:
+			return event.Event{}, fmt.Errorf("error updating event")
+		case errors.Is(err, storage.ErrorEventNotFound):
+			return event.Event{}, ErrEventNotFound
+		case errors.Is(err, storage.ErrorNotEnoughSeats):
+			return event.Event{}, ErrNotEnoughSeats
+		case errors.Is(err, storage.ErrorNotRecurring):
+			return event.Event{}, ErrNotRecurring
+		}
+	}
+
+	s.notifyPromoted(ctx, promoted)
+
+	if notify {
+		for _, rev := range revisions {
+			if len(event.Diff(rev.Old, rev.New)) == 0 {
+				continue
+			}
+
+			if err := s.evtRepo.QueueEventChange(ctx, rev.Old, changeBatchDelay, changeBatchMaxDelay); err != nil {
+				log.Error("could not queue change notifications",
+					slog.String("occurrence_id", rev.New.ID.String()),
+					slog.String("err", err.Error()),
+				)
+			}
+		}
+	}
+
+	log.Info("occurrences updated", slog.Int("count", len(revisions)))
+
+	for _, rev := range revisions {
+		if rev.New.ID == evt.ID {
+			return rev.New, nil
+		}
+	}
+
+	return s.evtRepo.GetEvent(ctx, evt.ID)
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// notifySeriesEnrolled emails the user one confirmation of the enrollment in
+// the series, with an invite holding every confirmed occurrence. Every
+// occurrence is described from its own row, since moving one occurrence
+// leaves the others in place.
+func (s *Service) notifySeriesEnrolled(ctx context.Context, usr user.User, enrollments []enrollment.Enrollment) {
+	const op = "service.notifySeriesEnrolled"
+
+	if len(enrollments) == 0 {
+		return
+	}
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", enrollments[0].EventId.String()),
+		slog.String("user_id", usr.ID.String()),
+	)
+
+	ids := make([]uuid.UUID, 0, len(enrollments))
+	for _, enrlmnt := range enrollments {
+		ids = append(ids, enrlmnt.EventId)
+	}
+
+	evts, err := s.evtRepo.GetEvents(ctx, ids)
+	if err != nil {
+		log.Error("error getting events", slog.String("err", err.Error()))
+		return
+	}
+
+	byID := make(map[uuid.UUID]event.Event, len(evts))
+	for _, evt := range evts {
+		byID[evt.ID] = evt
+	}
+
+	first, ok := byID[enrollments[0].EventId]
+	if !ok {
+		log.Error("first occurrence not found")
+		return
+	}
+
+	organizer := s.organizerOf(ctx, first)
+
+	// the occurrences usually share the place, it is looked up once
+	locations := make(map[orb.Point]string)
+	locationOf := func(evt event.Event) string {
+		location, ok := locations[evt.Location]
+		if !ok {
+			location = s.describeLocation(ctx, evt.Location)
+			locations[evt.Location] = location
+		}
+		return location
+	}
+
+	var vevents []ical.Event
+	for _, enrlmnt := range enrollments {
+		evt, ok := byID[enrlmnt.EventId]
+		if !ok || enrlmnt.Status != enrollment.StatusConfirmed {
+			continue
+		}
+		vevents = append(vevents, calendarEvent(evt, locationOf(evt), organizer))
+	}
+
+	content, err := letter.NewSeriesEnrollmentConfirmation(letter.ParseLocale(usr.Locale), usr.Name, first.Title, locationOf(first), first.Date.In(zoneFor(usr, first)))
+	if err != nil {
+		log.Error("error rendering series enrollment email", slog.String("err", err.Error()))
+		return
+	}
+
+	var attachments []letter.Attachment
+	if len(vevents) > 0 {
+		attachments = append(attachments, newCalendarInvite(ical.MethodRequest, vevents, usr))
+	}
+
+	if err := s.sendMessage(ctx, content, usr.Email, attachments...); err != nil {
+		log.Error("error sending series enrollment email", slog.String("err", err.Error()))
+	}
+}
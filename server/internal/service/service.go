@@ -7,6 +7,7 @@ import (
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/config"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
 	"github.com/google/uuid"
 	"github.com/paulmach/orb"
 )
@@ -18,36 +19,73 @@ type Cache interface {
 	SetLocation(ctx context.Context, point orb.Point, content string) error
 }
 
+// Mailer delivers emails. The implementation is chosen by configuration, so
+// development setups never talk to a real mail server.
+type Mailer interface {
+	Send(ctx context.Context, msg letter.Message) error
+}
+
 type Service struct {
-	log     *slog.Logger
-	usrRepo UserRepository
-	evtRepo EventRepository
-	tknScrt string
-	smtp    config.SMTPConfig
-	cache   Cache
+	log      *slog.Logger
+	usrRepo  UserRepository
+	evtRepo  EventRepository
+	sesRepo  SessionRepository
+	ntfRepo  NotificationRepository
+	mailRepo MailRepository
+	tokens   config.TokenConfig
+	urls     config.URLConfig
+	mailer   Mailer
+	cache    Cache
 }
 
 var (
-	ErrInvalidToken   = errors.New("invalid user token")
-	ErrUserNotFound   = errors.New("user with given email does not exist")
-	ErrEventNotFound  = errors.New("event with this id does not exist")
-	ErrNotEnoughSeats = errors.New("not enough seats")
+	ErrInvalidToken      = errors.New("invalid user token")
+	ErrUserNotFound      = errors.New("user with given email does not exist")
+	ErrEventNotFound     = errors.New("event with this id does not exist")
+	ErrNotEnoughSeats    = errors.New("not enough seats")
+	ErrSessionRevoked    = errors.New("session has been revoked or has expired")
+	ErrNotVerified       = errors.New("email address is not verified")
+	ErrVerified          = errors.New("email address is already verified")
+	ErrWrongPassword     = errors.New("wrong password")
+	ErrUserExists        = errors.New("user with given email already exists")
+	ErrForbidden         = errors.New("action is not permitted")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrNotEnrolled       = errors.New("user is not enrolled in the event")
+	ErrInvalidOffsets    = errors.New("invalid reminder offsets")
+	ErrEmailNotFound     = errors.New("failed email with this id does not exist")
+	ErrAlreadyEnrolled   = errors.New("user is already enrolled in the event")
+	ErrFeedNotFound      = errors.New("calendar feed does not exist")
+	ErrEventClosed       = errors.New("event does not take enrollments")
+	ErrEventCancelled    = errors.New("event is cancelled")
+	ErrNotDraft          = errors.New("event is not a draft")
+	ErrNotRecurring      = errors.New("event is not recurring")
+	ErrInvalidRule       = errors.New("invalid recurrence rule")
+	ErrEventTypeNotFound = errors.New("event type does not exist")
+	ErrEventTypeInUse    = errors.New("event type is in use")
 )
 
 func New(
 	log *slog.Logger,
 	usrRepo UserRepository,
 	evtRepo EventRepository,
-	tokenSecret string,
-	smtp config.SMTPConfig,
+	sesRepo SessionRepository,
+	ntfRepo NotificationRepository,
+	mailRepo MailRepository,
+	tokens config.TokenConfig,
+	urls config.URLConfig,
+	mailer Mailer,
 	cache Cache,
 ) *Service {
 	return &Service{
-		log:     log,
-		usrRepo: usrRepo,
-		evtRepo: evtRepo,
-		tknScrt: tokenSecret,
-		smtp:    smtp,
-		cache:   cache,
+		log:      log,
+		usrRepo:  usrRepo,
+		evtRepo:  evtRepo,
+		sesRepo:  sesRepo,
+		ntfRepo:  ntfRepo,
+		mailRepo: mailRepo,
+		tokens:   tokens,
+		urls:     urls,
+		mailer:   mailer,
+		cache:    cache,
 	}
 }
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/session"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type SessionRepository interface {
+	InsertSession(ctx context.Context, sess session.Session, refreshTokenHash string) (uuid.UUID, error)
+	GetSession(ctx context.Context, id uuid.UUID) (session.Session, error)
+	RotateSession(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (session.Session, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, userID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID uuid.UUID, current uuid.UUID) error
+}
+
+// RefreshTokens exchanges a refresh token for a new pair of tokens. The
+// presented refresh token is invalidated, so each one can be used only once.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (user.User, user.Tokens, error) {
+	const op = "service.RefreshTokens"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	newRefreshToken, err := generateSecretToken()
+	if err != nil {
+		log.Error("error generating refresh token", slog.String("err", err.Error()))
+		return user.User{}, user.Tokens{}, fmt.Errorf("error creating token")
+	}
+
+	refreshExpiresAt := time.Now().Add(s.tokens.RefreshTTL)
+
+	sess, err := s.sesRepo.RotateSession(ctx, hashToken(refreshToken), hashToken(newRefreshToken), refreshExpiresAt)
+	if err != nil {
+		log.Error("error rotating session", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorSessionNotFound) {
+			return user.User{}, user.Tokens{}, ErrInvalidToken
+		}
+		return user.User{}, user.Tokens{}, fmt.Errorf("error refreshing session")
+	}
+
+	log = log.With(slog.String("session_id", sess.ID.String()))
+
+	usr, err := s.usrRepo.GetUserByID(ctx, sess.UserID)
+	if err != nil {
+		log.Error("error getting user", slog.String("err", err.Error()))
+		return user.User{}, user.Tokens{}, ErrUserNotFound
+	}
+
+	usr.Password = ""
+
+	accessToken, accessExpiresAt, err := s.generateAccessToken(usr.ID, sess.ID)
+	if err != nil {
+		log.Error("error creating token", slog.String("err", err.Error()))
+		return user.User{}, user.Tokens{}, fmt.Errorf("error creating token")
+	}
+
+	return usr, user.Tokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     newRefreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func (s *Service) SignOut(ctx context.Context, sessionID uuid.UUID) error {
+	const op = "service.SignOut"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("session_id", sessionID.String()),
+	)
+
+	err := s.sesRepo.RevokeSession(ctx, sessionID)
+	if err != nil {
+		log.Error("error revoking session", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorSessionNotFound) {
+			return ErrSessionRevoked
+		}
+		return fmt.Errorf("error signing out")
+	}
+
+	return nil
+}
+
+func (s *Service) SignOutAll(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.SignOutAll"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	err := s.sesRepo.RevokeAllSessions(ctx, userID)
+	if err != nil {
+		log.Error("error revoking sessions", slog.String("err", err.Error()))
+		return fmt.Errorf("error signing out")
+	}
+
+	return nil
+}
+
+func (s *Service) checkSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	sess, err := s.sesRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if sess.UserID != userID {
+		return ErrSessionRevoked
+	}
+
+	if sess.RevokedAt != nil || time.Now().After(sess.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+
+	return nil
+}
+
+// generateTokens opens a new session for the user and issues an access token
+// bound to it together with the session's first refresh token.
+func (s *Service) generateTokens(ctx context.Context, usr user.User) (user.Tokens, error) {
+	refreshToken, err := generateSecretToken()
+	if err != nil {
+		return user.Tokens{}, err
+	}
+
+	refreshExpiresAt := time.Now().Add(s.tokens.RefreshTTL)
+
+	sessionID, err := s.sesRepo.InsertSession(ctx, session.Session{
+		UserID:    usr.ID,
+		ExpiresAt: refreshExpiresAt,
+	}, hashToken(refreshToken))
+	if err != nil {
+		return user.Tokens{}, err
+	}
+
+	accessToken, accessExpiresAt, err := s.generateAccessToken(usr.ID, sessionID)
+	if err != nil {
+		return user.Tokens{}, err
+	}
+
+	return user.Tokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func (s *Service) generateAccessToken(userID, sessionID uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.tokens.AccessTTL)
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, user.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID.String(),
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}).SignedString([]byte(s.tokens.Secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return accessToken, expiresAt, nil
+}
+
+func generateSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used for every secret token kept in the database, so a leaked
+// table does not hand out usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,17 +6,37 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepository interface {
 	GetUser(ctx context.Context, email string) (user.User, error)
-	InsertUser(ctx context.Context, usr user.User) error
+	GetUserByID(ctx context.Context, id uuid.UUID) (user.User, error)
+	InsertUser(ctx context.Context, usr user.User) (uuid.UUID, error)
+	VerifyUser(ctx context.Context, id uuid.UUID, email string) error
+	UpdateUser(ctx context.Context, id uuid.UUID, name, lastName, locale, timeZone *string) (user.User, error)
+	ChangeEmail(ctx context.Context, id uuid.UUID, email string) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	DeleteUser(ctx context.Context, id uuid.UUID) ([]enrollment.Enrollment, error)
+	GetAllUsers(ctx context.Context, limit, offset int) ([]user.User, error)
+	SetUserRole(ctx context.Context, id uuid.UUID, role user.Role) error
+	InsertPasswordReset(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	CountPasswordResets(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	QueuePasswordResetRequest(ctx context.Context, email string) error
+	ClaimPasswordResetRequests(ctx context.Context, limit int, lease time.Duration) ([]user.ResetRequest, error)
+	DeletePasswordResetRequest(ctx context.Context, id uuid.UUID) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uuid.UUID, error)
+	SetCalendarToken(ctx context.Context, userID uuid.UUID, tokenHash string) error
+	DeleteCalendarToken(ctx context.Context, userID uuid.UUID) error
+	GetUserByCalendarToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
 }
 
-func (s *Service) Login(ctx context.Context, email, password string) (user.User, string, error) {
+func (s *Service) Login(ctx context.Context, email, password string) (user.User, user.Tokens, error) {
 	const op = "service.Login"
 
 	log := s.log.With(
@@ -27,33 +47,37 @@ func (s *Service) Login(ctx context.Context, email, password string) (user.User,
 	usr, err := s.usrRepo.GetUser(ctx, email)
 	if err != nil {
 		log.Error("error getting user", slog.String("error", err.Error()))
-		return user.User{}, "", fmt.Errorf("invalid email or password")
+		return user.User{}, user.Tokens{}, fmt.Errorf("invalid email or password")
 	}
 
 	if !checkPasswordHash(password, usr.Password) {
 		log.Error("incorrect password")
-		return user.User{}, "", fmt.Errorf("invalid email or password")
+		return user.User{}, user.Tokens{}, fmt.Errorf("invalid email or password")
 	}
 
 	usr.Password = ""
 
-	token, err := generateTokens(s.tknScrt, usr)
+	tokens, err := s.generateTokens(ctx, usr)
 	if err != nil {
 		log.Error("error creating token", slog.String("error", err.Error()))
-		return user.User{}, "", fmt.Errorf("error creating token")
+		return user.User{}, user.Tokens{}, fmt.Errorf("error creating token")
 	}
 
-	return usr, token, nil
+	return usr, tokens, nil
 }
 
-func (s *Service) LoginByToken(ctx context.Context, token string) (user.User, error) {
+// LoginByToken validates an access token and returns its owner together with
+// the id of the session the token was issued for. Tokens of revoked or expired
+// sessions are rejected even if the token itself has not expired yet. The user
+// is read from storage, so a changed role applies to the very next request.
+func (s *Service) LoginByToken(ctx context.Context, token string) (user.User, uuid.UUID, error) {
 	const op = "service.LoginByToken"
 
 	log := s.log.With(
 		slog.String("op", op),
 	)
 
-	secret := []byte(s.tknScrt)
+	secret := []byte(s.tokens.Secret)
 
 	data, err := jwt.ParseWithClaims(token, &user.UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
@@ -64,19 +88,45 @@ func (s *Service) LoginByToken(ctx context.Context, token string) (user.User, er
 
 	if err != nil {
 		log.Error("invalid jwt token", slog.String("err", err.Error()))
-		return user.User{}, ErrInvalidToken
+		return user.User{}, uuid.UUID{}, ErrInvalidToken
 	}
 
-	if claims, ok := data.Claims.(*user.UserClaims); ok && data.Valid {
-		log.Info("token validated successfully", slog.Any("email", claims.Payload.Email))
-		return claims.Payload, nil
+	claims, ok := data.Claims.(*user.UserClaims)
+	if !ok || !data.Valid {
+		log.Error("invalid jwt token")
+		return user.User{}, uuid.UUID{}, ErrInvalidToken
 	}
 
-	log.Error("invalid jwt token")
-	return user.User{}, ErrInvalidToken
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		log.Error("token has no user id", slog.String("err", err.Error()))
+		return user.User{}, uuid.UUID{}, ErrInvalidToken
+	}
+
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		log.Error("token has no session id", slog.String("err", err.Error()))
+		return user.User{}, uuid.UUID{}, ErrInvalidToken
+	}
+
+	if err := s.checkSession(ctx, userID, sessionID); err != nil {
+		log.Error("session is not active", slog.String("session_id", sessionID.String()), slog.String("err", err.Error()))
+		return user.User{}, uuid.UUID{}, ErrInvalidToken
+	}
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error("token owner not found", slog.String("err", err.Error()))
+		return user.User{}, uuid.UUID{}, ErrInvalidToken
+	}
+
+	usr.Password = ""
+
+	log.Info("token validated successfully", slog.String("user_id", usr.ID.String()))
+	return usr, sessionID, nil
 }
 
-func (s *Service) Register(ctx context.Context, usr user.User) (string, error) {
+func (s *Service) Register(ctx context.Context, usr user.User) (user.Tokens, error) {
 	const op = "service.Register"
 
 	log := s.log.With(
@@ -86,27 +136,36 @@ func (s *Service) Register(ctx context.Context, usr user.User) (string, error) {
 
 	var err error
 
+	// new accounts may only enroll until an admin makes them organizers, the
+	// accounts made before roles were introduced kept their events
+	usr.Role = user.RoleUser
+	usr.Locale = string(letter.ParseLocale(usr.Locale))
+
 	usr.Password, err = hashPassword(usr.Password)
 	if err != nil {
 		log.Error("error hashing password", slog.String("error", err.Error()))
-		return "", fmt.Errorf("invalid password")
+		return user.Tokens{}, fmt.Errorf("invalid password")
 	}
 
-	err = s.usrRepo.InsertUser(ctx, usr)
+	usr.ID, err = s.usrRepo.InsertUser(ctx, usr)
 	if err != nil {
 		log.Error("error creating user", slog.String("error", err.Error()))
-		return "", fmt.Errorf("error creating user")
+		return user.Tokens{}, fmt.Errorf("error creating user")
 	}
 
 	usr.Password = ""
 
-	token, err := generateTokens(s.tknScrt, usr)
+	if err := s.sendVerificationEmail(ctx, usr); err != nil {
+		log.Error("error sending verification email", slog.String("error", err.Error()))
+	}
+
+	tokens, err := s.generateTokens(ctx, usr)
 	if err != nil {
 		log.Error("error creating user", slog.String("error", err.Error()))
-		return "", fmt.Errorf("error creating user")
+		return user.Tokens{}, fmt.Errorf("error creating user")
 	}
 
-	return token, nil
+	return tokens, nil
 }
 
 func hashPassword(password string) (string, error) {
@@ -118,16 +177,3 @@ func checkPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
-
-func generateTokens(accessSecret string, usr user.User) (string, error) {
-
-	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"exp":     time.Now().Add(time.Hour * 24 * 365).Unix(),
-		"payload": usr,
-	}).SignedString([]byte(accessSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return accessToken, nil
-}
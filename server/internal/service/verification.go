@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	verificationAudience = "email_verification"
+	emailChangeAudience  = "email_change"
+)
+
+type emailClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// VerifyEmail confirms the address the verification token was issued for.
+// Verifying an already verified address is not an error.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	const op = "service.VerifyEmail"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	userID, email, err := s.parseEmailToken(token, verificationAudience)
+	if err != nil {
+		log.Error("invalid verification token", slog.String("err", err.Error()))
+		return ErrInvalidToken
+	}
+
+	log = log.With(slog.String("user_id", userID.String()))
+
+	err = s.usrRepo.VerifyUser(ctx, userID, email)
+	if err != nil {
+		log.Error("error verifying user", slog.String("err", err.Error()))
+		if errors.Is(err, storage.ErrorNoUser) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error verifying email")
+	}
+
+	log.Info("email verified")
+
+	return nil
+}
+
+func (s *Service) ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.ResendVerificationEmail"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error("error getting user", slog.String("err", err.Error()))
+		return ErrUserNotFound
+	}
+
+	if usr.IsVerified() {
+		return ErrVerified
+	}
+
+	if err := s.sendVerificationEmail(ctx, usr); err != nil {
+		log.Error("error sending verification email", slog.String("err", err.Error()))
+		return fmt.Errorf("error sending verification email")
+	}
+
+	return nil
+}
+
+// RequestEmailChange sends a confirmation link to the new address. The email
+// of the account stays the same until the link is followed.
+func (s *Service) RequestEmailChange(ctx context.Context, userID uuid.UUID, password, newEmail string) error {
+	const op = "service.RequestEmailChange"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+
+	usr, err := s.usrRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error("error getting user", slog.String("err", err.Error()))
+		return ErrUserNotFound
+	}
+
+	if !checkPasswordHash(password, usr.Password) {
+		log.Error("incorrect password")
+		return ErrWrongPassword
+	}
+
+	if _, err := s.usrRepo.GetUser(ctx, newEmail); err == nil {
+		log.Error("email is already taken")
+		return ErrUserExists
+	}
+
+	token, err := s.generateEmailToken(usr.ID, newEmail, emailChangeAudience, s.tokens.VerificationTTL)
+	if err != nil {
+		log.Error("error generating token", slog.String("err", err.Error()))
+		return fmt.Errorf("error requesting email change")
+	}
+
+	link := fmt.Sprintf("%s/users/email/confirm?%s", s.urls.Public, url.Values{"token": {token}}.Encode())
+
+	content, err := letter.NewEmailChangeLetter(letter.ParseLocale(usr.Locale), usr.Name, link)
+	if err != nil {
+		log.Error("error rendering confirmation email", slog.String("err", err.Error()))
+		return fmt.Errorf("error sending confirmation email")
+	}
+
+	if err := s.sendMessage(ctx, content, newEmail); err != nil {
+		log.Error("error sending confirmation email", slog.String("err", err.Error()))
+		return fmt.Errorf("error sending confirmation email")
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange switches the account to the address the token was
+// issued for. The new address counts as verified.
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) error {
+	const op = "service.ConfirmEmailChange"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	userID, email, err := s.parseEmailToken(token, emailChangeAudience)
+	if err != nil {
+		log.Error("invalid email change token", slog.String("err", err.Error()))
+		return ErrInvalidToken
+	}
+
+	log = log.With(slog.String("user_id", userID.String()))
+
+	err = s.usrRepo.ChangeEmail(ctx, userID, email)
+	if err != nil {
+		log.Error("error changing email", slog.String("err", err.Error()))
+		switch {
+		case errors.Is(err, storage.ErrorNoUser):
+			return ErrUserNotFound
+		case errors.Is(err, storage.ErrorUserExists):
+			return ErrUserExists
+		default:
+			return fmt.Errorf("error changing email")
+		}
+	}
+
+	log.Info("email changed")
+
+	return nil
+}
+
+func (s *Service) sendVerificationEmail(ctx context.Context, usr user.User) error {
+	const op = "service.sendVerificationEmail"
+
+	token, err := s.generateEmailToken(usr.ID, usr.Email, verificationAudience, s.tokens.VerificationTTL)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	link := fmt.Sprintf("%s/users/verify?%s", s.urls.Public, url.Values{"token": {token}}.Encode())
+
+	content, err := letter.NewVerificationLetter(letter.ParseLocale(usr.Locale), usr.Name, link)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return s.sendMessage(ctx, content, usr.Email)
+}
+
+// requireVerified is checked before actions that may result in mail being
+// sent to the user's address.
+func (s *Service) requireVerified(usr user.User) error {
+	if !usr.IsVerified() {
+		return ErrNotVerified
+	}
+	return nil
+}
+
+// generateEmailToken signs a token proving that the user controls an email
+// address. The audience keeps tokens of one purpose from being accepted for
+// another.
+func (s *Service) generateEmailToken(userID uuid.UUID, email, audience string, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, emailClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}).SignedString([]byte(s.tokens.Secret))
+}
+
+func (s *Service) parseEmailToken(token, audience string) (uuid.UUID, string, error) {
+	var claims emailClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(s.tokens.Secret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+
+	if claims.Email == "" {
+		return uuid.UUID{}, "", ErrInvalidToken
+	}
+
+	return userID, claims.Email, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/ical"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/google/uuid"
+)
+
+// GetEnrollment returns the enrollment of the user in the event together with
+// the place in the waitlist, which is zero for confirmed enrollments.
+func (s *Service) GetEnrollment(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, int, error) {
+	const op = "service.GetEnrollment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	enrlmnt, err := s.evtRepo.GetEventSubscription(ctx, eventID, userID)
+	if err != nil {
+		log.Error("error accessing event enrollment", slog.String("err", err.Error()))
+		return enrollment.Enrollment{}, 0, ErrNotEnrolled
+	}
+
+	if enrlmnt.Status != enrollment.StatusWaitlist {
+		return enrlmnt, 0, nil
+	}
+
+	position, err := s.evtRepo.GetWaitlistPosition(ctx, enrlmnt.Id)
+	if err != nil {
+		log.Error("error getting waitlist position", slog.String("err", err.Error()))
+		return enrollment.Enrollment{}, 0, fmt.Errorf("error getting waitlist position")
+	}
+
+	return enrlmnt, position, nil
+}
+
+// GetWaitlist returns the waitlist of the event if the user is its creator or
+// an admin.
+func (s *Service) GetWaitlist(ctx context.Context, userID uuid.UUID, role user.Role, eventID uuid.UUID) ([]enrollment.Attendee, error) {
+	const op = "service.GetWaitlist"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", eventID.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	if err := s.authorizeEventOwner(ctx, userID, role, eventID); err != nil {
+		log.Error("user may not see the waitlist", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	attendees, err := s.evtRepo.GetWaitlist(ctx, eventID)
+	if err != nil {
+		log.Error("error getting waitlist", slog.String("err", err.Error()))
+		return nil, fmt.Errorf("error getting waitlist")
+	}
+
+	return attendees, nil
+}
+
+// notifyPromoted emails the users whose enrollments were moved from the
+// waitlist. The letters are in the outbound queue by the time it returns.
+func (s *Service) notifyPromoted(ctx context.Context, promoted []enrollment.Enrollment) {
+	const op = "service.notifyPromoted"
+
+	if len(promoted) == 0 {
+		return
+	}
+
+	for _, enrlmnt := range promoted {
+		log := s.log.With(
+			slog.String("op", op),
+			slog.String("event_id", enrlmnt.EventId.String()),
+			slog.String("user_id", enrlmnt.UserID.String()),
+		)
+
+		log.Info("enrollment promoted from the waitlist")
+
+		usr, err := s.usrRepo.GetUserByID(ctx, enrlmnt.UserID)
+		if err != nil {
+			log.Error("error getting user", slog.String("err", err.Error()))
+			continue
+		}
+
+		evt, err := s.evtRepo.GetEvent(ctx, enrlmnt.EventId)
+		if err != nil {
+			log.Error("error getting event", slog.String("err", err.Error()))
+			continue
+		}
+
+		content, err := letter.NewPromotionNotification(letter.ParseLocale(usr.Locale), usr.Name, evt.Title, evt.Date.In(zoneFor(usr, evt)))
+		if err != nil {
+			log.Error("error rendering promotion email", slog.String("err", err.Error()))
+			continue
+		}
+
+		invite := newInvite(ical.MethodRequest, evt, s.describeLocation(ctx, evt.Location), s.organizerOf(ctx, evt), usr)
+
+		if err := s.sendMessage(ctx, content, usr.Email, invite); err != nil {
+			log.Error("error sending promotion email", slog.String("err", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/google/uuid"
+)
+
+const attendeesQuery = `
+	SELECT e.id, u.id, u.name, u.last_name, u.email, e.status,
+		CASE WHEN e.status = 'waitlist'
+			THEN ROW_NUMBER() OVER (PARTITION BY e.status ORDER BY e.created_at, e.id)
+			ELSE 0
+		END,
+		e.created_at
+	FROM enrollments e
+	JOIN users u ON u.id = e.user_id
+	WHERE e.event_id = $1
+	ORDER BY e.created_at, e.id
+	`
+
+// GetAttendees returns a page of the enrollments of the event in the order
+// they were made.
+func (s *Storage) GetAttendees(ctx context.Context, eventID uuid.UUID, limit, offset int) ([]enrollment.Attendee, error) {
+	const op = "storage.postgres.GetAttendees"
+
+	var attendees = []enrollment.Attendee{}
+
+	err := s.StreamAttendees(ctx, eventID, limit, offset, func(a enrollment.Attendee) error {
+		attendees = append(attendees, a)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return attendees, nil
+}
+
+// StreamAttendees calls fn for every enrollment of the event without loading
+// the whole list in memory. A non-positive limit means no limit.
+func (s *Storage) StreamAttendees(ctx context.Context, eventID uuid.UUID, limit, offset int, fn func(enrollment.Attendee) error) error {
+	const op = "storage.postgres.StreamAttendees"
+
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	query := attendeesQuery + `LIMIT $2 OFFSET $3`
+
+	rows, err := s.conn.Query(ctx, query, eventID, limitArg, offset)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a enrollment.Attendee
+
+		err := rows.Scan(
+			&a.EnrollmentID,
+			&a.UserID,
+			&a.Name,
+			&a.LastName,
+			&a.Email,
+			&a.Status,
+			&a.Position,
+			&a.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
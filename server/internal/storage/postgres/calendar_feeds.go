@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SetCalendarToken replaces the feed token of the user, the old link stops
+// working.
+func (s *Storage) SetCalendarToken(ctx context.Context, userID uuid.UUID, tokenHash string) error {
+	const op = "storage.postgres.SetCalendarToken"
+
+	query := `
+	INSERT INTO calendar_feeds (user_id, token_hash)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET token_hash = EXCLUDED.token_hash,
+	created_at = NOW()
+	`
+
+	if _, err := s.conn.Exec(ctx, query, userID, tokenHash); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return storage.ErrorNoUser
+			}
+		}
+
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteCalendarToken(ctx context.Context, userID uuid.UUID) error {
+	const op = "storage.postgres.DeleteCalendarToken"
+
+	cmdTag, err := s.conn.Exec(ctx, `DELETE FROM calendar_feeds WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorFeedNotFound
+	}
+
+	return nil
+}
+
+// GetUserByCalendarToken returns the owner of the feed.
+func (s *Storage) GetUserByCalendarToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	const op = "storage.postgres.GetUserByCalendarToken"
+
+	var userID uuid.UUID
+
+	err := s.conn.QueryRow(ctx, `SELECT user_id FROM calendar_feeds WHERE token_hash = $1`, tokenHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.UUID{}, storage.ErrorFeedNotFound
+		}
+
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return userID, nil
+}
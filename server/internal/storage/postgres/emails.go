@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/letter"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// EnqueueEmails stores the messages for delivery, either all of them or none.
+func (s *Storage) EnqueueEmails(ctx context.Context, msgs []letter.Message) error {
+	const op = "storage.postgres.EnqueueEmails"
+
+	batch := &pgx.Batch{}
+	for _, msg := range msgs {
+		batch.Queue(`INSERT INTO outbound_emails (recipient, subject, html, text, attachments) VALUES ($1, $2, $3, $4, $5)`, msg.To, msg.Subject, msg.HTML, msg.Text, attachments(msg))
+	}
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return nil
+}
+
+// ClaimEmails leases up to limit emails that are due for delivery. Rows
+// locked by other replicas are skipped.
+func (s *Storage) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]letter.Outbound, error) {
+	const op = "storage.postgres.ClaimEmails"
+
+	query := `
+	WITH due AS (
+		SELECT id
+		FROM outbound_emails
+		WHERE next_attempt_at <= NOW()
+		AND (locked_until IS NULL OR locked_until < NOW())
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE outbound_emails o
+	SET locked_until = NOW() + make_interval(secs => $2),
+	attempts = o.attempts + 1
+	FROM due
+	WHERE o.id = due.id
+	RETURNING o.id, o.recipient, o.subject, o.html, o.text, o.attachments, o.attempts
+	`
+
+	rows, err := s.conn.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	emails, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (letter.Outbound, error) {
+		var e letter.Outbound
+		err := row.Scan(&e.ID, &e.Message.To, &e.Message.Subject, &e.Message.HTML, &e.Message.Text, &e.Message.Attachments, &e.Attempts)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return emails, nil
+}
+
+func (s *Storage) MarkEmailSent(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgres.MarkEmailSent"
+
+	if _, err := s.conn.Exec(ctx, `DELETE FROM outbound_emails WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+// RetryEmail releases the lease and postpones the next attempt by retryIn.
+func (s *Storage) RetryEmail(ctx context.Context, id uuid.UUID, reason string, retryIn time.Duration) error {
+	const op = "storage.postgres.RetryEmail"
+
+	query := `
+	UPDATE outbound_emails
+	SET next_attempt_at = NOW() + make_interval(secs => $3),
+	locked_until = NULL,
+	last_error = $2
+	WHERE id = $1
+	`
+
+	if _, err := s.conn.Exec(ctx, query, id, reason, retryIn.Seconds()); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeadLetterEmail moves the email out of the queue into the dead-letter
+// table.
+func (s *Storage) DeadLetterEmail(ctx context.Context, id uuid.UUID, reason string) error {
+	const op = "storage.postgres.DeadLetterEmail"
+
+	query := `
+	WITH failed AS (
+		DELETE FROM outbound_emails
+		WHERE id = $1
+		RETURNING id, recipient, subject, html, text, attachments, attempts, created_at
+	)
+	INSERT INTO dead_letter_emails (id, recipient, subject, html, text, attachments, attempts, last_error, created_at)
+	SELECT id, recipient, subject, html, text, attachments, attempts, $2, created_at
+	FROM failed
+	`
+
+	if _, err := s.conn.Exec(ctx, query, id, reason); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetDeadLetters(ctx context.Context, limit, offset int) ([]letter.DeadLetter, error) {
+	const op = "storage.postgres.GetDeadLetters"
+
+	query := `
+	SELECT id, recipient, subject, attempts, COALESCE(last_error, ''), created_at, failed_at
+	FROM dead_letter_emails
+	ORDER BY failed_at DESC
+	LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.conn.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	letters, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (letter.DeadLetter, error) {
+		var l letter.DeadLetter
+		err := row.Scan(&l.ID, &l.To, &l.Subject, &l.Attempts, &l.LastError, &l.CreatedAt, &l.FailedAt)
+		return l, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return letters, nil
+}
+
+// RedriveDeadLetter puts the failed email back into the queue with a fresh
+// set of attempts.
+func (s *Storage) RedriveDeadLetter(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgres.RedriveDeadLetter"
+
+	query := `
+	WITH failed AS (
+		DELETE FROM dead_letter_emails
+		WHERE id = $1
+		RETURNING id, recipient, subject, html, text, attachments, created_at
+	)
+	INSERT INTO outbound_emails (id, recipient, subject, html, text, attachments, created_at)
+	SELECT id, recipient, subject, html, text, attachments, created_at
+	FROM failed
+	`
+
+	cmdTag, err := s.conn.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorEmailNotFound
+	}
+
+	return nil
+}
+
+// attachments never returns nil, a nil slice would be stored as JSON null.
+func attachments(msg letter.Message) []letter.Attachment {
+	if msg.Attachments == nil {
+		return []letter.Attachment{}
+	}
+	return msg.Attachments
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// QueueEventChange records that the event was edited. The letter is due
+// after delay; every further edit pushes it back by delay again, but never
+// past maxDelay after the first edit. The event as it was before the first
+// edit is kept.
+func (s *Storage) QueueEventChange(ctx context.Context, old event.Event, delay, maxDelay time.Duration) error {
+	const op = "storage.postgres.QueueEventChange"
+
+	query := `
+	INSERT INTO pending_event_changes (event_id, old_event, send_at)
+	VALUES ($1, $2, NOW() + make_interval(secs => $3))
+	ON CONFLICT (event_id) DO UPDATE
+	SET send_at = LEAST(
+		NOW() + make_interval(secs => $3),
+		pending_event_changes.created_at + make_interval(secs => $4)
+	)
+	`
+
+	if _, err := s.conn.Exec(ctx, query, old.ID, old, delay.Seconds(), maxDelay.Seconds()); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return storage.ErrorEventNotFound
+			}
+		}
+
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+// ClaimEventChanges takes up to limit due changes out of the table. Rows
+// locked by other replicas are skipped. An edit made after the claim starts
+// a new batch.
+func (s *Storage) ClaimEventChanges(ctx context.Context, limit int) ([]event.PendingChange, error) {
+	const op = "storage.postgres.ClaimEventChanges"
+
+	query := `
+	DELETE FROM pending_event_changes
+	WHERE event_id IN (
+		SELECT event_id
+		FROM pending_event_changes
+		WHERE send_at <= NOW()
+		ORDER BY send_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING event_id, old_event, created_at
+	`
+
+	rows, err := s.conn.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	changes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (event.PendingChange, error) {
+		var c event.PendingChange
+		err := row.Scan(&c.EventID, &c.Old, &c.CreatedAt)
+		return c, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return changes, nil
+}
+
+// RestoreEventChange puts back a claimed change that could not be handled.
+// If the event was edited again in the meantime, the two batches are merged
+// and the older version of the event is kept.
+func (s *Storage) RestoreEventChange(ctx context.Context, change event.PendingChange, retryIn time.Duration) error {
+	const op = "storage.postgres.RestoreEventChange"
+
+	query := `
+	INSERT INTO pending_event_changes (event_id, old_event, send_at, created_at)
+	VALUES ($1, $2, NOW() + make_interval(secs => $3), $4)
+	ON CONFLICT (event_id) DO UPDATE
+	SET old_event = EXCLUDED.old_event,
+	created_at = EXCLUDED.created_at,
+	send_at = LEAST(pending_event_changes.send_at, EXCLUDED.send_at)
+	`
+
+	if _, err := s.conn.Exec(ctx, query, change.EventID, change.Old, retryIn.Seconds(), change.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return storage.ErrorEventNotFound
+			}
+		}
+
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
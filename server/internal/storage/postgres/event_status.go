@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// eventStatus is the status of the event aliased as e, as users see it.
+// Published events become completed when they start, without a write.
+const eventStatus = `CASE WHEN e.status = 'published' AND e.date < NOW() THEN 'completed' ELSE e.status END`
+
+// requireOpen fails with storage.ErrorEventClosed unless the event takes
+// enrollments, which only published events that have not started do.
+func requireOpen(ctx context.Context, tx pgx.Tx, eventID uuid.UUID) error {
+	var open bool
+
+	query := `SELECT ` + eventStatus + ` = 'published' FROM events e WHERE e.id = $1`
+	if err := tx.QueryRow(ctx, query, eventID).Scan(&open); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrorEventNotFound
+		}
+		return err
+	}
+
+	if !open {
+		return storage.ErrorEventClosed
+	}
+
+	return nil
+}
+
+// CancelEvent marks the event cancelled and returns its new sequence for the
+// cancellation invites. Enrollments are kept, so attendees still see the
+// event, but reminders and pending change letters are dropped.
+func (s *Storage) CancelEvent(ctx context.Context, id uuid.UUID, reason *string) (int, error) {
+	const op = "storage.postgres.CancelEvent"
+
+	var sequence int
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = lockEvent(ctx, tx, id); err != nil {
+		if errors.Is(err, storage.ErrorEventNotFound) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query := `
+	UPDATE events
+	SET status = 'cancelled',
+	cancelled_at = NOW(),
+	cancellation_reason = $2,
+	sequence = sequence + 1
+	WHERE id = $1 AND status <> 'cancelled'
+	RETURNING sequence
+	`
+
+	err = tx.QueryRow(ctx, query, id, reason).Scan(&sequence)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrorEventCancelled
+		}
+		return 0, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `
+	DELETE FROM scheduled_notifications
+	WHERE sent_at IS NULL
+	AND enrollment_id IN (SELECT id FROM enrollments WHERE event_id = $1)
+	`
+
+	if _, err = tx.Exec(ctx, query, id); err != nil {
+		return 0, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM pending_event_changes WHERE event_id = $1`, id); err != nil {
+		return 0, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return sequence, nil
+}
+
+// PublishEvent publishes the draft right away, whatever its schedule. The
+// draft occurrences of a recurring event are published together, and so
+// are the ones created later.
+func (s *Storage) PublishEvent(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgres.PublishEvent"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+	UPDATE events
+	SET status = 'published',
+	publish_at = NOW()
+	WHERE status = 'draft'
+	AND EXISTS (SELECT 1 FROM events WHERE id = $1 AND status = 'draft')
+	AND (id = $1 OR series_id = (SELECT series_id FROM events WHERE id = $1))
+	RETURNING id
+	`
+
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if len(ids) == 0 {
+		var exists bool
+		query = `SELECT EXISTS (SELECT 1 FROM events WHERE id = $1)`
+		if err = tx.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+			return fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		if !exists {
+			return storage.ErrorEventNotFound
+		}
+
+		return storage.ErrorNotDraft
+	}
+
+	if err = publishSeries(ctx, tx, ids); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return nil
+}
+
+// PublishDueEvents publishes the drafts whose time has come and returns
+// their ids. Replicas running it at once never publish an event twice.
+func (s *Storage) PublishDueEvents(ctx context.Context) ([]uuid.UUID, error) {
+	const op = "storage.postgres.PublishDueEvents"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+	UPDATE events
+	SET status = 'published'
+	WHERE status = 'draft' AND publish_at <= NOW()
+	RETURNING id
+	`
+
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = publishSeries(ctx, tx, ids); err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return ids, nil
+}
+
+// publishSeries marks the series of the published occurrences published, so
+// the occurrences generated later are not drafts.
+func publishSeries(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) error {
+	query := `
+	UPDATE event_series
+	SET template = jsonb_set(template, '{status}', '"published"')
+	WHERE id IN (SELECT series_id FROM events WHERE id = ANY($1))
+	`
+
+	_, err := tx.Exec(ctx, query, ids)
+	return err
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (s *Storage) GetEventTypes(ctx context.Context) ([]event.Type, error) {
+	const op = "storage.postgres.GetEventTypes"
+
+	rows, err := s.conn.Query(ctx, `SELECT id, names FROM event_types ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	types, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (event.Type, error) {
+		var t event.Type
+		err := row.Scan(&t.ID, &t.Names)
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return types, nil
+}
+
+func (s *Storage) GetEventType(ctx context.Context, id int) (event.Type, error) {
+	const op = "storage.postgres.GetEventType"
+
+	t := event.Type{ID: id}
+
+	err := s.conn.QueryRow(ctx, `SELECT names FROM event_types WHERE id = $1`, id).Scan(&t.Names)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return event.Type{}, storage.ErrorEventTypeNotFound
+		}
+		return event.Type{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return t, nil
+}
+
+func (s *Storage) InsertEventType(ctx context.Context, t *event.Type) error {
+	const op = "storage.postgres.InsertEventType"
+
+	err := s.conn.QueryRow(ctx, `INSERT INTO event_types (names) VALUES ($1) RETURNING id`, t.Names).Scan(&t.ID)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateEventType(ctx context.Context, t event.Type) error {
+	const op = "storage.postgres.UpdateEventType"
+
+	cmdTag, err := s.conn.Exec(ctx, `UPDATE event_types SET names = $2 WHERE id = $1`, t.ID, t.Names)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorEventTypeNotFound
+	}
+
+	return nil
+}
+
+// DeleteEventType deletes the type unless some event still has it.
+func (s *Storage) DeleteEventType(ctx context.Context, id int) error {
+	const op = "storage.postgres.DeleteEventType"
+
+	cmdTag, err := s.conn.Exec(ctx, `DELETE FROM event_types WHERE id = $1`, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return storage.ErrorEventTypeInUse
+			}
+		}
+
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorEventTypeNotFound
+	}
+
+	return nil
+}
+
+// setTags replaces the tags of the events. Tags no event had before are
+// created.
+func setTags(ctx context.Context, tx pgx.Tx, eventIDs []uuid.UUID, tags []string) error {
+	query := `INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`
+	if _, err := tx.Exec(ctx, query, tags); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM event_tags WHERE event_id = ANY($1)`, eventIDs); err != nil {
+		return err
+	}
+
+	query = `
+	INSERT INTO event_tags (event_id, tag_id)
+	SELECT e.id, t.id
+	FROM unnest($1::uuid[]) AS e(id)
+	CROSS JOIN tags t
+	WHERE t.name = ANY($2)
+	`
+
+	_, err := tx.Exec(ctx, query, eventIDs, tags)
+	return err
+}
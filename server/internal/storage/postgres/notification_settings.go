@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) GetReminderOffsets(ctx context.Context, userID uuid.UUID) ([]int, error) {
+	const op = "storage.postgres.GetReminderOffsets"
+
+	var offsets []int
+
+	err := s.conn.QueryRow(ctx, `SELECT reminder_offsets FROM users WHERE id = $1`, userID).Scan(&offsets)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrorNoUser
+		}
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return offsets, nil
+}
+
+// SetReminderOffsets stores the reminder offsets of the user and reschedules
+// the pending reminders of all upcoming events the user is enrolled in.
+func (s *Storage) SetReminderOffsets(ctx context.Context, userID uuid.UUID, offsets []int) error {
+	const op = "storage.postgres.SetReminderOffsets"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	cmdTag, err := tx.Exec(ctx, `UPDATE users SET reminder_offsets = $2 WHERE id = $1`, userID, offsets)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorNoUser
+	}
+
+	query := `
+	SELECT e.id, ev.id
+	FROM enrollments e
+	JOIN events ev ON ev.id = e.event_id
+	WHERE e.user_id = $1
+	AND e.status = 'confirmed'
+	AND e.reminder_offsets IS NULL
+	AND ev.date > NOW()
+	ORDER BY ev.id
+	FOR UPDATE OF ev
+	`
+
+	rows, err := tx.Query(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	type upcoming struct {
+		enrollmentID uuid.UUID
+		eventID      uuid.UUID
+	}
+
+	enrollments, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (upcoming, error) {
+		var u upcoming
+		err := row.Scan(&u.enrollmentID, &u.eventID)
+		return u, err
+	})
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	for _, u := range enrollments {
+		if err = scheduleReminders(ctx, tx, u.eventID, []uuid.UUID{u.enrollmentID}); err != nil {
+			return fmt.Errorf("op: %s, err: %v", op, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return nil
+}
+
+// SetEnrollmentReminderOffsets overrides the reminder offsets of the user for
+// one event and reschedules its pending reminders. A nil offsets removes the
+// override.
+func (s *Storage) SetEnrollmentReminderOffsets(ctx context.Context, eventID, userID uuid.UUID, offsets []int) error {
+	const op = "storage.postgres.SetEnrollmentReminderOffsets"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = lockEvent(ctx, tx, eventID); err != nil {
+		if errors.Is(err, storage.ErrorEventNotFound) {
+			return storage.ErrorNotEnrolled
+		}
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	var enrollmentID uuid.UUID
+
+	query := `
+	UPDATE enrollments
+	SET reminder_offsets = $3
+	WHERE event_id = $1 AND user_id = $2
+	RETURNING id
+	`
+
+	err = tx.QueryRow(ctx, query, eventID, userID, offsets).Scan(&enrollmentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrorNotEnrolled
+		}
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = scheduleReminders(ctx, tx, eventID, []uuid.UUID{enrollmentID}); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return nil
+}
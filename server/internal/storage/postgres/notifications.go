@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/notification"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// scheduleReminders brings the reminders of the event in line with its date,
+// its confirmed enrollments and their reminder offsets. Pending reminders are
+// replaced, reminders that are being sent right now are kept. A sent reminder
+// is kept while the event stays at the date it was sent for, so it goes out
+// once per date, and is dropped when the event moves, so the new date gets a
+// reminder of its own. When ids is nil the whole event is rescheduled,
+// otherwise only the listed enrollments. The caller must hold the lock on the
+// event row.
+func scheduleReminders(ctx context.Context, tx pgx.Tx, eventID uuid.UUID, ids []uuid.UUID) error {
+	query := `
+	DELETE FROM scheduled_notifications sn
+	USING enrollments e, events ev
+	WHERE sn.enrollment_id = e.id
+	AND ev.id = e.event_id
+	AND e.event_id = $1
+	AND ($2::uuid[] IS NULL OR e.id = ANY($2))
+	AND (
+		(sn.sent_at IS NULL AND (sn.locked_until IS NULL OR sn.locked_until < NOW()))
+		OR (sn.sent_at IS NOT NULL AND sn.send_at <> ev.date - make_interval(mins => sn.offset_minutes))
+	)
+	`
+
+	if _, err := tx.Exec(ctx, query, eventID, ids); err != nil {
+		return err
+	}
+
+	query = `
+	INSERT INTO scheduled_notifications (enrollment_id, offset_minutes, send_at)
+	SELECT e.id, o.minutes, ev.date - make_interval(mins => o.minutes)
+	FROM enrollments e
+	JOIN events ev ON ev.id = e.event_id
+	JOIN users u ON u.id = e.user_id
+	CROSS JOIN LATERAL unnest(COALESCE(e.reminder_offsets, u.reminder_offsets)) AS o(minutes)
+	WHERE e.event_id = $1
+	AND ($2::uuid[] IS NULL OR e.id = ANY($2))
+	AND e.status = 'confirmed'
+	AND ev.date - make_interval(mins => o.minutes) > NOW()
+	ON CONFLICT (enrollment_id, offset_minutes) DO NOTHING
+	`
+
+	if _, err := tx.Exec(ctx, query, eventID, ids); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ClaimNotifications leases up to limit due reminders. Rows locked by other
+// replicas are skipped, and a leased reminder is not handed out again until
+// the lease expires, so a crashed worker delays a reminder but never loses it.
+func (s *Storage) ClaimNotifications(ctx context.Context, limit int, lease time.Duration, maxAttempts int) ([]notification.Scheduled, error) {
+	const op = "storage.postgres.ClaimNotifications"
+
+	query := `
+	WITH due AS (
+		SELECT sn.id
+		FROM scheduled_notifications sn
+		JOIN enrollments e ON e.id = sn.enrollment_id
+		JOIN events ev ON ev.id = e.event_id
+		WHERE sn.sent_at IS NULL
+		AND sn.send_at <= NOW()
+		AND (sn.locked_until IS NULL OR sn.locked_until < NOW())
+		AND sn.attempts < $3
+		AND ev.date > NOW()
+		ORDER BY sn.send_at
+		LIMIT $1
+		FOR UPDATE OF sn SKIP LOCKED
+	)
+	UPDATE scheduled_notifications sn
+	SET locked_until = NOW() + make_interval(secs => $2),
+	attempts = sn.attempts + 1
+	FROM due, enrollments e
+	WHERE sn.id = due.id AND e.id = sn.enrollment_id
+	RETURNING sn.id, sn.enrollment_id, e.user_id, e.event_id, sn.offset_minutes, sn.send_at, sn.attempts
+	`
+
+	rows, err := s.conn.Query(ctx, query, limit, lease.Seconds(), maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	notifications, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (notification.Scheduled, error) {
+		var n notification.Scheduled
+		err := row.Scan(&n.ID, &n.EnrollmentID, &n.UserID, &n.EventID, &n.OffsetMinutes, &n.SendAt, &n.Attempts)
+		return n, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return notifications, nil
+}
+
+func (s *Storage) MarkNotificationSent(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgres.MarkNotificationSent"
+
+	query := `
+	UPDATE scheduled_notifications
+	SET sent_at = NOW(), locked_until = NULL, last_error = NULL
+	WHERE id = $1
+	`
+
+	if _, err := s.conn.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+// MarkNotificationFailed releases the lease and keeps the reminder away from
+// workers for retryIn.
+func (s *Storage) MarkNotificationFailed(ctx context.Context, id uuid.UUID, reason string, retryIn time.Duration) error {
+	const op = "storage.postgres.MarkNotificationFailed"
+
+	query := `
+	UPDATE scheduled_notifications
+	SET locked_until = NOW() + make_interval(secs => $3), last_error = $2
+	WHERE id = $1 AND sent_at IS NULL
+	`
+
+	if _, err := s.conn.Exec(ctx, query, id, reason, retryIn.Seconds()); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+func enrollmentIDs(enrollments []enrollment.Enrollment) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(enrollments))
+	for _, enrlmnt := range enrollments {
+		ids = append(ids, enrlmnt.Id)
+	}
+	return ids
+}
@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (s *Storage) InsertPasswordReset(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	const op = "storage.postgres.InsertPasswordReset"
+
+	query := `
+	INSERT INTO password_resets(token_hash, user_id, expires_at)
+	VALUES ($1, $2, $3)
+	`
+
+	_, err := s.conn.Exec(ctx, query, tokenHash, userID, expiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return storage.ErrorNoUser
+			}
+		}
+
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CountPasswordResets(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	const op = "storage.postgres.CountPasswordResets"
+
+	var count int
+
+	query := `
+	SELECT COUNT(*)
+	FROM password_resets
+	WHERE user_id = $1 AND created_at > $2
+	`
+
+	err := s.conn.QueryRow(ctx, query, userID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return count, nil
+}
+
+// ResetPassword consumes the reset token, stores the new password hash and
+// revokes every session of the user in a single transaction. All the other
+// outstanding reset tokens of the user are consumed as well.
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uuid.UUID, error) {
+	const op = "storage.postgres.ResetPassword"
+
+	var userID uuid.UUID
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+	UPDATE password_resets
+	SET used_at = NOW()
+	WHERE token_hash = $1
+	AND used_at IS NULL
+	AND expires_at > NOW()
+	RETURNING user_id
+	`
+
+	err = tx.QueryRow(ctx, query, tokenHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.UUID{}, storage.ErrorResetNotFound
+		}
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `
+	UPDATE password_resets
+	SET used_at = NOW()
+	WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	if _, err = tx.Exec(ctx, query, userID); err != nil {
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `
+	UPDATE users
+	SET password = $2
+	WHERE id = $1
+	`
+
+	if _, err = tx.Exec(ctx, query, userID, passwordHash); err != nil {
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `
+	UPDATE sessions
+	SET revoked_at = NOW()
+	WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err = tx.Exec(ctx, query, userID); err != nil {
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return uuid.UUID{}, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return userID, nil
+}
+
+// QueuePasswordResetRequest stores the request for ClaimPasswordResetRequests
+// without looking the address up.
+func (s *Storage) QueuePasswordResetRequest(ctx context.Context, email string) error {
+	const op = "storage.postgres.QueuePasswordResetRequest"
+
+	if _, err := s.conn.Exec(ctx, `INSERT INTO password_reset_requests (email) VALUES ($1)`, email); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+// ClaimPasswordResetRequests leases the oldest requests that are not leased
+// already. Rows locked by other replicas are skipped. A request stays queued
+// until DeletePasswordResetRequest, so one whose lease runs out is claimed
+// again.
+func (s *Storage) ClaimPasswordResetRequests(ctx context.Context, limit int, lease time.Duration) ([]user.ResetRequest, error) {
+	const op = "storage.postgres.ClaimPasswordResetRequests"
+
+	query := `
+	WITH due AS (
+		SELECT id
+		FROM password_reset_requests
+		WHERE claimed_until IS NULL OR claimed_until < NOW()
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE password_reset_requests r
+	SET claimed_until = NOW() + make_interval(secs => $2)
+	FROM due
+	WHERE r.id = due.id
+	RETURNING r.id, r.email
+	`
+
+	rows, err := s.conn.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	requests, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (user.ResetRequest, error) {
+		var r user.ResetRequest
+		err := row.Scan(&r.ID, &r.Email)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return requests, nil
+}
+
+// DeletePasswordResetRequest takes a handled request off the queue.
+func (s *Storage) DeletePasswordResetRequest(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgres.DeletePasswordResetRequest"
+
+	if _, err := s.conn.Exec(ctx, `DELETE FROM password_reset_requests WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
 	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
@@ -59,16 +58,21 @@ func (s *Storage) GetUser(ctx context.Context, email string) (user.User, error)
 	var usr user.User
 
 	query := `
-	SELECT name, last_name, email, password
+	SELECT id, name, last_name, email, password, role, locale, time_zone, verified_at
 	FROM users
 	WHERE email=$1
 	`
 
 	err := s.conn.QueryRow(ctx, query, email).Scan(
+		&usr.ID,
 		&usr.Name,
 		&usr.LastName,
 		&usr.Email,
 		&usr.Password,
+		&usr.Role,
+		&usr.Locale,
+		&usr.TimeZone,
+		&usr.VerifiedAt,
 	)
 
 	if err != nil {
@@ -82,21 +86,148 @@ func (s *Storage) GetUser(ctx context.Context, email string) (user.User, error)
 	return usr, nil
 }
 
-func (s *Storage) InsertUser(ctx context.Context, usr user.User) error {
+func (s *Storage) GetUserByID(ctx context.Context, id uuid.UUID) (user.User, error) {
+	const op = "storage.postgres.GetUserByID"
+
+	var usr user.User
+
+	query := `
+	SELECT id, name, last_name, email, password, role, locale, time_zone, verified_at
+	FROM users
+	WHERE id=$1
+	`
+
+	err := s.conn.QueryRow(ctx, query, id).Scan(
+		&usr.ID,
+		&usr.Name,
+		&usr.LastName,
+		&usr.Email,
+		&usr.Password,
+		&usr.Role,
+		&usr.Locale,
+		&usr.TimeZone,
+		&usr.VerifiedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.User{}, storage.ErrorNoUser
+		}
+
+		return user.User{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return usr, nil
+}
+
+func (s *Storage) InsertUser(ctx context.Context, usr user.User) (uuid.UUID, error) {
 	const op = "storage.postgres.InsertUser"
 
+	var id uuid.UUID
+
 	query := `
-	INSERT INTO users(name, last_name, email, password)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO users(name, last_name, email, password, role, locale)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id
 	`
 
-	_, err := s.conn.Exec(ctx, query,
+	err := s.conn.QueryRow(ctx, query,
 		usr.Name,
 		usr.LastName,
 		usr.Email,
 		usr.Password,
+		usr.Role,
+		usr.Locale,
+	).Scan(&id)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23505" {
+				return uuid.UUID{}, storage.ErrorUserExists
+			}
+		}
+
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// VerifyUser marks the user as verified if the email is still the one the
+// verification was requested for.
+func (s *Storage) VerifyUser(ctx context.Context, id uuid.UUID, email string) error {
+	const op = "storage.postgres.VerifyUser"
+
+	query := `
+	UPDATE users
+	SET verified_at = COALESCE(verified_at, NOW())
+	WHERE id = $1 AND email = $2
+	`
+
+	cmdTag, err := s.conn.Exec(ctx, query, id, email)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorNoUser
+	}
+
+	return nil
+}
+
+// UpdateUser changes the fields that are set. An empty time zone removes
+// the zone of the user.
+func (s *Storage) UpdateUser(ctx context.Context, id uuid.UUID, name, lastName, locale, timeZone *string) (user.User, error) {
+	const op = "storage.postgres.UpdateUser"
+
+	var usr user.User
+
+	query := `
+	UPDATE users
+	SET name = COALESCE($2, name),
+	last_name = COALESCE($3, last_name),
+	locale = COALESCE($4, locale),
+	time_zone = CASE WHEN $5::text IS NULL THEN time_zone ELSE NULLIF($5, '') END
+	WHERE id = $1
+	RETURNING id, name, last_name, email, role, locale, time_zone, verified_at
+	`
+
+	err := s.conn.QueryRow(ctx, query, id, name, lastName, locale, timeZone).Scan(
+		&usr.ID,
+		&usr.Name,
+		&usr.LastName,
+		&usr.Email,
+		&usr.Role,
+		&usr.Locale,
+		&usr.TimeZone,
+		&usr.VerifiedAt,
 	)
 
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.User{}, storage.ErrorNoUser
+		}
+
+		return user.User{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return usr, nil
+}
+
+// ChangeEmail sets a new, already confirmed, email address.
+func (s *Storage) ChangeEmail(ctx context.Context, id uuid.UUID, email string) error {
+	const op = "storage.postgres.ChangeEmail"
+
+	query := `
+	UPDATE users
+	SET email = $2,
+	verified_at = NOW()
+	WHERE id = $1
+	`
+
+	cmdTag, err := s.conn.Exec(ctx, query, id, email)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -108,33 +239,206 @@ func (s *Storage) InsertUser(ctx context.Context, usr user.User) error {
 		return fmt.Errorf("op: %s, err: %v", op, err)
 	}
 
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorNoUser
+	}
+
+	return nil
+}
+
+func (s *Storage) GetAllUsers(ctx context.Context, limit, offset int) ([]user.User, error) {
+	const op = "storage.postgres.GetAllUsers"
+
+	var users = []user.User{}
+
+	query := `
+	SELECT id, name, last_name, email, role, locale, time_zone, verified_at
+	FROM users
+	ORDER BY email
+	LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.conn.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var usr user.User
+
+		err := rows.Scan(
+			&usr.ID,
+			&usr.Name,
+			&usr.LastName,
+			&usr.Email,
+			&usr.Role,
+			&usr.Locale,
+			&usr.TimeZone,
+			&usr.VerifiedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		users = append(users, usr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) SetUserRole(ctx context.Context, id uuid.UUID, role user.Role) error {
+	const op = "storage.postgres.SetUserRole"
+
+	query := `
+	UPDATE users
+	SET role = $2
+	WHERE id = $1
+	`
+
+	cmdTag, err := s.conn.Exec(ctx, query, id, role)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorNoUser
+	}
+
 	return nil
 }
 
+func (s *Storage) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	const op = "storage.postgres.UpdatePassword"
+
+	query := `
+	UPDATE users
+	SET password = $2
+	WHERE id = $1
+	`
+
+	cmdTag, err := s.conn.Exec(ctx, query, id, passwordHash)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorNoUser
+	}
+
+	return nil
+}
+
+// DeleteUser deletes the user together with the events they created and
+// their enrollments. The seats the user held on other events are given back
+// before the enrollments cascade away and are handed to the oldest waitlisted
+// enrollments, the promoted enrollments are returned.
+func (s *Storage) DeleteUser(ctx context.Context, id uuid.UUID) ([]enrollment.Enrollment, error) {
+	const op = "storage.postgres.DeleteUser"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+	UPDATE events
+	SET available_seats = available_seats + 1
+	WHERE id IN (
+		SELECT event_id FROM enrollments WHERE user_id = $1 AND status = 'confirmed'
+	)
+	AND creator_id <> $1
+	AND has_unlimited_seats = FALSE
+	RETURNING id
+	`
+
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	eventIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `DELETE FROM users WHERE id = $1`
+
+	cmdTag, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return nil, storage.ErrorNoUser
+	}
+
+	var promoted []enrollment.Enrollment
+	for _, eventID := range eventIDs {
+		p, err := promoteWaitlist(ctx, tx, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+		promoted = append(promoted, p...)
+
+		if len(p) == 0 {
+			continue
+		}
+
+		if err = scheduleReminders(ctx, tx, eventID, enrollmentIDs(p)); err != nil {
+			return nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return promoted, nil
+}
+
 func (s *Storage) InsertEvent(ctx context.Context, evt *event.Event) error {
 	const op = "storage.postgres.InsertEvent"
 
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	query := `
-	INSERT INTO events (title, type, date, total_seats, available_seats, creator_email, location, has_unlimited_seats, description)
-	VALUES ($1, $2, $3, $4, $5, $6, ST_GeomFromText($7, 4326), $8, $9)
+	INSERT INTO events (title, type, date, total_seats, available_seats, creator_id, location, has_unlimited_seats, description, status, publish_at, ends_at, time_zone)
+	VALUES ($1, $2, $3, $4, $5, $6, ST_GeomFromText($7, 4326), $8, $9, $10, $11, $12, $13)
 	RETURNING id
 	`
 
-	err := s.conn.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		evt.Title,
 		evt.Type,
 		evt.Date,
 		evt.TotalSeats,
 		evt.AvailableSeats,
-		evt.CreatorEmail,
+		evt.CreatorID,
 		fmt.Sprintf("POINT(%f %f)", evt.Location.Lon(), evt.Location.Lat()),
 		evt.HasUnlimitedSeats,
 		evt.Description,
+		evt.Status,
+		evt.PublishAt,
+		evt.EndsAt,
+		evt.TimeZone,
 	).Scan(&evt.ID)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" && pgErr.ConstraintName == "fk_events_type" {
+				return storage.ErrorEventTypeNotFound
+			}
 			if pgErr.Code == "23503" {
 				return storage.ErrorNoUser
 			}
@@ -143,48 +447,132 @@ func (s *Storage) InsertEvent(ctx context.Context, evt *event.Event) error {
 		return fmt.Errorf("op: %s, err: %v", op, err)
 	}
 
+	if err = setTags(ctx, tx, []uuid.UUID{evt.ID}, evt.Tags); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
 	return nil
 }
 
-func (s *Storage) UpdateEvent(ctx context.Context, evt event.Event) error {
+// UpdateEvent replaces the event and recomputes its available seats from the
+// confirmed enrollments. The event row is locked first, so enrollments made
+// concurrently are either counted or wait for the update to finish. Seats
+// that become free are handed to the waitlist, the promoted enrollments are
+// returned.
+func (s *Storage) UpdateEvent(ctx context.Context, evt event.Event) (event.Event, []enrollment.Enrollment, error) {
 	const op = "storage.postgres.UpdateEvent"
 
-	query := `
-	UPDATE events
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return event.Event{}, nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	evt, promoted, err := updateEvent(ctx, tx, evt)
+	if err != nil {
+		if errors.Is(err, storage.ErrorEventNotFound) || errors.Is(err, storage.ErrorEventCancelled) || errors.Is(err, storage.ErrorNotEnoughSeats) {
+			return event.Event{}, nil, err
+		}
+		return event.Event{}, nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return event.Event{}, nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return evt, promoted, nil
+}
+
+// updateEvent does the work of UpdateEvent within the transaction.
+func updateEvent(ctx context.Context, tx pgx.Tx, evt event.Event) (event.Event, []enrollment.Enrollment, error) {
+	if err := lockEvent(ctx, tx, evt.ID); err != nil {
+		return event.Event{}, nil, err
+	}
+
+	var cancelled bool
+	query := `SELECT status = 'cancelled' FROM events WHERE id = $1`
+	if err := tx.QueryRow(ctx, query, evt.ID).Scan(&cancelled); err != nil {
+		return event.Event{}, nil, err
+	}
+
+	if cancelled {
+		return event.Event{}, nil, storage.ErrorEventCancelled
+	}
+
+	var enrolled int
+	query = `SELECT COUNT(*) FROM enrollments WHERE event_id = $1 AND status = 'confirmed'`
+	if err := tx.QueryRow(ctx, query, evt.ID).Scan(&enrolled); err != nil {
+		return event.Event{}, nil, err
+	}
+
+	available := evt.TotalSeats
+	if !evt.IsUnlimited() {
+		if enrolled > evt.TotalSeats {
+			return event.Event{}, nil, storage.ErrorNotEnoughSeats
+		}
+		available -= enrolled
+	}
+
+	// before the update, so the returned event has the new tags
+	if err := setTags(ctx, tx, []uuid.UUID{evt.ID}, evt.Tags); err != nil {
+		return event.Event{}, nil, err
+	}
+
+	query = `
+	UPDATE events e
 	SET title = $2,
 	type = $3,
 	date = $4,
 	total_seats = $5,
 	available_seats = $10,
-	creator_email = $6,
+	creator_id = $6,
 	location = ST_GeomFromText($7, 4326),
 	has_unlimited_seats = $8,
-	description = $9
-	WHERE id = $1
-	`
-
-	commandTag, err := s.conn.Exec(ctx, query,
+	description = $9,
+	publish_at = CASE WHEN e.status = 'draft' THEN $11 ELSE e.publish_at END,
+	ends_at = $12,
+	time_zone = $13,
+	sequence = e.sequence + 1
+	WHERE e.id = $1
+	RETURNING ` + eventColumns
+
+	updated, err := scanEvent(tx.QueryRow(ctx, query,
 		evt.ID.String(),
 		evt.Title,
 		evt.Type,
 		evt.Date,
 		evt.TotalSeats,
-		evt.CreatorEmail,
+		evt.CreatorID,
 		fmt.Sprintf("POINT(%f %f)", evt.Location.Lon(), evt.Location.Lat()),
 		evt.HasUnlimitedSeats,
 		evt.Description,
-		evt.AvailableSeats,
-	)
+		available,
+		evt.PublishAt,
+		evt.EndsAt,
+		evt.TimeZone,
+	))
+	if err != nil {
+		return event.Event{}, nil, err
+	}
 
+	promoted, err := promoteWaitlist(ctx, tx, evt.ID)
 	if err != nil {
-		return fmt.Errorf("op: %s, err: %v", op, err)
+		return event.Event{}, nil, err
 	}
 
-	if commandTag.RowsAffected() <= 0 {
-		return storage.ErrorEventNotFound
+	if err = scheduleReminders(ctx, tx, evt.ID, nil); err != nil {
+		return event.Event{}, nil, err
 	}
 
-	return nil
+	if !updated.IsUnlimited() {
+		updated.AvailableSeats -= len(promoted)
+	}
+
+	return updated, promoted, nil
 }
 
 func (s *Storage) DeleteEvent(ctx context.Context, id uuid.UUID) error {
@@ -207,36 +595,9 @@ func (s *Storage) DeleteEvent(ctx context.Context, id uuid.UUID) error {
 func (s *Storage) GetEvent(ctx context.Context, id uuid.UUID) (event.Event, error) {
 	const op = "storage.postgres.GetEvent"
 
-	var ev event.Event
-	var locationWKT string
-	var hasUnlimitedSeats bool
-
-	query := `
-	SELECT id, title, type, date, total_seats, available_seats, creator_email, 
-	       ST_AsText(location), has_unlimited_seats, description
-	FROM events
-	WHERE id = $1
-	`
-
-	err := s.conn.QueryRow(ctx, query, id).Scan(
-		&ev.ID,
-		&ev.Title,
-		&ev.Type,
-		&ev.Date,
-		&ev.TotalSeats,
-		&ev.AvailableSeats,
-		&ev.CreatorEmail,
-		&locationWKT,
-		&hasUnlimitedSeats,
-		&ev.Description,
-	)
-
-	if hasUnlimitedSeats {
-		ev.HasUnlimitedSeats = "true"
-	} else {
-		ev.HasUnlimitedSeats = "false"
-	}
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE e.id = $1`
 
+	ev, err := scanEvent(s.conn.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return event.Event{}, storage.ErrorEventNotFound
@@ -244,180 +605,337 @@ func (s *Storage) GetEvent(ctx context.Context, id uuid.UUID) (event.Event, erro
 		return event.Event{}, fmt.Errorf("op: %s, err: %v", op, err)
 	}
 
-	ev.Location, err = parseWKT(locationWKT)
+	return ev, nil
+}
+
+// GetEvents returns the events with the given ids in no particular order. Ids
+// that match no event are left out.
+func (s *Storage) GetEvents(ctx context.Context, ids []uuid.UUID) ([]event.Event, error) {
+	const op = "storage.postgres.GetEvents"
+
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE e.id = ANY($1)`
+
+	rows, err := s.conn.Query(ctx, query, ids)
 	if err != nil {
-		return event.Event{}, fmt.Errorf("op: %s, err: invalid location format: %v", op, err)
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
 	}
+	defer rows.Close()
 
-	return ev, nil
+	var events []event.Event
+	for rows.Next() {
+		ev, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+		events = append(events, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return events, nil
 }
 
-func (s *Storage) GetAllEvents(ctx context.Context, limit, offset *int, eventType *int, creatorEmail *string, before, after *time.Time, location *orb.Point, radius *float64, visitorEmail *string) ([]event.Event, error) {
-	const op = "storage.postgres.GetAllEvents"
+// searchText is the text of the event aliased as e that snippets are cut
+// from. It is escaped, since snippets are HTML.
+const searchText = `replace(replace(replace(e.title || '. ' || coalesce(e.description, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;')`
 
-	var events = []event.Event{}
+const snippetOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
 
-	query := `
-	SELECT e.id, e.title, e.type, e.date, e.total_seats, e.available_seats, e.creator_email, ST_AsText(e.location), e.has_unlimited_seats, e.description
-	FROM events e
-	`
+// GetAllEvents lists a page of the events matching the filter. Pages are
+// keyset paginated on the sort key and the id, so events created or changed
+// between requests do not shift the pages.
+func (s *Storage) GetAllEvents(ctx context.Context, f event.Filter) (event.Page, error) {
+	const op = "storage.postgres.GetAllEvents"
+
+	var page = event.Page{Events: []event.Event{}}
 
 	var conditions []string
 	var args []interface{}
 	argCount := 1
 
-	if eventType != nil {
-		conditions = append(conditions, fmt.Sprintf("e.type = $%d", argCount))
-		args = append(args, *eventType)
+	if len(f.Types) > 0 {
+		conditions = append(conditions, fmt.Sprintf("e.type = ANY($%d)", argCount))
+		args = append(args, f.Types)
 		argCount++
 	}
 
-	if creatorEmail != nil {
-		conditions = append(conditions, fmt.Sprintf("e.creator_email = $%d", argCount))
-		args = append(args, *creatorEmail)
+	if len(f.Tags) > 0 {
+		conditions = append(conditions, fmt.Sprintf(`
+		e.id IN (
+			SELECT et.event_id FROM event_tags et JOIN tags t ON t.id = et.tag_id
+			WHERE t.name = ANY($%d)
+			GROUP BY et.event_id
+			HAVING COUNT(*) = $%d
+		)`, argCount, argCount+1))
+		args = append(args, f.Tags, len(f.Tags))
+		argCount += 2
+	}
+
+	if f.CreatorID != nil {
+		conditions = append(conditions, fmt.Sprintf("e.creator_id = $%d", argCount))
+		args = append(args, *f.CreatorID)
 		argCount++
 	}
 
-	if before != nil {
+	if f.Before != nil {
 		conditions = append(conditions, fmt.Sprintf("e.date < $%d", argCount))
-		args = append(args, *before)
+		args = append(args, *f.Before)
 		argCount++
 	}
 
-	if after != nil {
+	if f.After != nil {
 		conditions = append(conditions, fmt.Sprintf("e.date > $%d", argCount))
-		args = append(args, *after)
+		args = append(args, *f.After)
+		argCount++
+	}
+
+	// events overlapping the window from to, or happening at the moment if
+	// both are the same
+	if f.From != nil {
+		conditions = append(conditions, fmt.Sprintf("e.ends_at > $%d", argCount))
+		args = append(args, *f.From)
+		argCount++
+	}
+
+	if f.To != nil {
+		conditions = append(conditions, fmt.Sprintf("e.date < $%d", argCount))
+		args = append(args, *f.To)
 		argCount++
 	}
 
-	if location != nil && radius != nil {
+	if f.Location != nil && f.Radius != nil {
 		conditions = append(conditions, fmt.Sprintf(
 			"ST_DWithin(e.location, ST_SetSRID(ST_MakePoint($%d, $%d), 4326), $%d)", argCount, argCount+1, argCount+2))
-		args = append(args, location.Lon(), location.Lat(), *radius)
+		args = append(args, f.Location.Lon(), f.Location.Lat(), *f.Radius)
 		argCount += 3
 	}
 
-	if visitorEmail != nil {
+	if f.VisitorID != nil {
 		conditions = append(conditions, fmt.Sprintf(`
 		e.id IN (
-			SELECT event_id FROM enrollments WHERE user_email = $%d
-		)`, argCount))
-		args = append(args, *visitorEmail)
+			SELECT event_id FROM enrollments WHERE user_id = $%d AND ($%d::text IS NULL OR status = $%d)
+		)`, argCount, argCount+1, argCount+1))
+		args = append(args, *f.VisitorID, f.VisitorStatus)
+		argCount += 2
+	}
+
+	if f.Status != nil {
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", eventStatus, argCount))
+		args = append(args, *f.Status)
+		argCount++
+	} else {
+		// drafts are only listed when asked for
+		conditions = append(conditions, "e.status <> 'draft'")
+	}
+
+	// the query matches in either language
+	var tsQuery string
+	if f.Query != nil {
+		tsQuery = fmt.Sprintf("(websearch_to_tsquery('russian', $%d) || websearch_to_tsquery('english', $%d))", argCount, argCount)
+		conditions = append(conditions, "e.search @@ "+tsQuery)
+		args = append(args, *f.Query)
 		argCount++
 	}
 
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if f.WithTotal {
+		query := `SELECT COUNT(*) FROM events e WHERE ` + strings.Join(conditions, " AND ")
+
+		var total int
+		if err := s.conn.QueryRow(ctx, query, args...).Scan(&total); err != nil {
+			return event.Page{}, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+		page.Total = &total
 	}
 
-	if limit != nil && offset != nil {
-		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
-		args = append(args, limit, offset)
+	// the key is read back from the cursor as text and cast to its type
+	sortKey, keyType := "e.date", "timestamptz"
+	switch f.Sort {
+	case event.SortDistance:
+		if f.Location == nil {
+			return event.Page{}, fmt.Errorf("op: %s, err: sorting by distance needs a location", op)
+		}
+		sortKey = fmt.Sprintf("ST_Distance(e.location, ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography)", argCount, argCount+1)
+		keyType = "float8"
+		args = append(args, f.Location.Lon(), f.Location.Lat())
+		argCount += 2
+	case event.SortSeats:
+		// events without a limit have the most seats left
+		sortKey, keyType = "CASE WHEN e.has_unlimited_seats THEN 2147483647 ELSE e.available_seats END", "int"
+	case event.SortCreatedAt:
+		sortKey, keyType = "e.created_at", "timestamp"
+	case event.SortRelevance:
+		if f.Query == nil {
+			return event.Page{}, fmt.Errorf("op: %s, err: sorting by relevance needs a query", op)
+		}
+		sortKey, keyType = "ts_rank(e.search, "+tsQuery+")", "real"
+	}
+
+	snippet := "NULL::text"
+	if f.Query != nil {
+		snippet = "ts_headline('russian', " + searchText + ", " + tsQuery + ", '" + snippetOptions + "')"
+	}
+
+	direction, order := ">", "ASC"
+	if f.Desc {
+		direction, order = "<", "DESC"
+	}
+
+	if f.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, e.id) %s ($%d::%s, $%d)", sortKey, direction, argCount, keyType, argCount+1))
+		args = append(args, f.Cursor.Key, f.Cursor.ID)
+		argCount += 2
+	}
+
+	query := `SELECT ` + eventColumns + `, (` + sortKey + `)::text, ` + snippet + ` FROM events e WHERE ` + strings.Join(conditions, " AND ")
+	query += fmt.Sprintf(" ORDER BY %s %s, e.id %s", sortKey, order, order)
+
+	// one more than the page to tell whether another follows
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, f.Limit+1)
 	}
 
 	rows, err := s.conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+		return event.Page{}, fmt.Errorf("op: %s, err: %v", op, err)
 	}
 	defer rows.Close()
 
+	var keys []string
 	for rows.Next() {
-		var ev event.Event
-		var locationWKT string
-		var hasUnlimitedSeats bool
-		err := rows.Scan(
-			&ev.ID,
-			&ev.Title,
-			&ev.Type,
-			&ev.Date,
-			&ev.TotalSeats,
-			&ev.AvailableSeats,
-			&ev.CreatorEmail,
-			&locationWKT,
-			&hasUnlimitedSeats,
-			&ev.Description,
-		)
+		var key string
+		var snippet *string
+		ev, err := scanEvent(rows, &key, &snippet)
 		if err != nil {
-			return nil, fmt.Errorf("op: %s, err: %v", op, err)
+			return event.Page{}, fmt.Errorf("op: %s, err: %v", op, err)
 		}
+		ev.Snippet = snippet
+		page.Events = append(page.Events, ev)
+		keys = append(keys, key)
+	}
 
-		if hasUnlimitedSeats {
-			ev.HasUnlimitedSeats = "true"
-		} else {
-			ev.HasUnlimitedSeats = "false"
+	if err := rows.Err(); err != nil {
+		return event.Page{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if f.Limit > 0 && len(page.Events) > f.Limit {
+		page.Events = page.Events[:f.Limit]
+		page.NextCursor = &event.Cursor{
+			Sort: f.Sort,
+			Desc: f.Desc,
+			Key:  keys[f.Limit-1],
+			ID:   page.Events[f.Limit-1].ID,
 		}
+	}
 
-		ev.Location, err = parseWKT(locationWKT)
-		if err != nil {
-			return nil, fmt.Errorf("op: %s, err: invalid location format: %v", op, err)
+	return page, nil
+}
+
+// SubscribeToEvent reserves a seat and creates the enrollment in one
+// transaction. The event row is locked and the seat is taken with a conditional
+// decrement, so concurrent enrollments can never push the event over capacity.
+// When no seats are left the enrollment is put on the waitlist instead.
+func (s *Storage) SubscribeToEvent(ctx context.Context, eventId uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, error) {
+	const op = "storage.postgres.SubscribeToEvent"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return enrollment.Enrollment{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	enrlmnt, err := enroll(ctx, tx, eventId, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrorEventNotFound) || errors.Is(err, storage.ErrorEventClosed) || errors.Is(err, storage.ErrorAlreadyEnrolled) {
+			return enrollment.Enrollment{}, err
 		}
-		events = append(events, ev)
+		return enrollment.Enrollment{}, fmt.Errorf("op: %s, err: %v", op, err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	if err = tx.Commit(ctx); err != nil {
+		return enrollment.Enrollment{}, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
 	}
 
-	return events, nil
+	return enrlmnt, nil
 }
 
-func (s *Storage) SubscribeToEvent(ctx context.Context, eventId uuid.UUID, email string) (uuid.UUID, error) {
-	const op = "storage.postgres.SubscribeToEvent"
+// enroll does the work of SubscribeToEvent within the transaction.
+func enroll(ctx context.Context, tx pgx.Tx, eventID uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, error) {
+	enrlmnt := enrollment.Enrollment{
+		UserID:  userID,
+		EventId: eventID,
+		Status:  enrollment.StatusConfirmed,
+	}
 
-	var id uuid.UUID
+	if err := lockEvent(ctx, tx, eventID); err != nil {
+		return enrollment.Enrollment{}, err
+	}
 
-	tx, err := s.conn.Begin(ctx)
-	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	if err := requireOpen(ctx, tx, eventID); err != nil {
+		return enrollment.Enrollment{}, err
 	}
 
 	query := `
-	INSERT INTO enrollments(user_email, event_id)
-	VALUES ($1, $2)
-	RETURNING id
+	UPDATE events
+	SET available_seats = CASE WHEN has_unlimited_seats THEN available_seats ELSE available_seats - 1 END
+	WHERE id = $1 AND (has_unlimited_seats OR available_seats > 0)
 	`
 
-	err = tx.QueryRow(ctx, query, email, eventId).Scan(&id)
+	commandTag, err := tx.Exec(ctx, query, eventID)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+		return enrollment.Enrollment{}, err
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		enrlmnt.Status = enrollment.StatusWaitlist
 	}
 
 	query = `
-	UPDATE events
-	SET available_seats = available_seats - 1
-	WHERE id = $1 AND has_unlimited_seats = 'f'
+	INSERT INTO enrollments(user_id, event_id, status)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at
 	`
 
-	_, err = tx.Exec(ctx, query, eventId)
+	err = tx.QueryRow(ctx, query, userID, eventID, enrlmnt.Status).Scan(&enrlmnt.Id, &enrlmnt.CreatedAt)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return enrollment.Enrollment{}, storage.ErrorAlreadyEnrolled
+		}
+		return enrollment.Enrollment{}, err
 	}
 
-	if err = tx.Commit(ctx); err != nil {
-		return uuid.UUID{}, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	if enrlmnt.Status == enrollment.StatusConfirmed {
+		if err = scheduleReminders(ctx, tx, eventID, []uuid.UUID{enrlmnt.Id}); err != nil {
+			return enrollment.Enrollment{}, err
+		}
 	}
 
-	return id, nil
+	return enrlmnt, nil
 }
 
-func (s *Storage) GetEventSubscription(ctx context.Context, enrollmentEventId uuid.UUID, email string) (enrollment.Enrollment, error) {
+func (s *Storage) GetEventSubscription(ctx context.Context, enrollmentEventId uuid.UUID, userID uuid.UUID) (enrollment.Enrollment, error) {
 	const op = "storage.postgres.GetEventSubscription"
 
 	var enrlmnt enrollment.Enrollment
 
 	query := `
-	SELECT id, created_at, user_email, event_id
+	SELECT id, created_at, user_id, event_id, status, reminder_offsets
 	FROM enrollments
-	WHERE user_email = $1 AND event_id = $2
+	WHERE user_id = $1 AND event_id = $2
 	`
 
 	var enrlmntId, eventId string
 
-	err := s.conn.QueryRow(ctx, query, email, enrollmentEventId).Scan(
+	err := s.conn.QueryRow(ctx, query, userID, enrollmentEventId).Scan(
 		&enrlmntId,
 		&enrlmnt.CreatedAt,
-		&enrlmnt.UserEmail,
+		&enrlmnt.UserID,
 		&eventId,
+		&enrlmnt.Status,
+		&enrlmnt.ReminderOffsets,
 	)
 
 	if err != nil {
@@ -437,28 +955,75 @@ func (s *Storage) GetEventSubscription(ctx context.Context, enrollmentEventId uu
 	return enrlmnt, nil
 }
 
-func (s *Storage) UnsubscribeFromEvent(ctx context.Context, enrollmentId uuid.UUID) error {
+// UnsubscribeFromEvent deletes the enrollment. If it held a seat, the seat is
+// handed to the first enrollment on the waitlist, the promoted enrollments are
+// returned.
+func (s *Storage) UnsubscribeFromEvent(ctx context.Context, enrollmentId uuid.UUID) ([]enrollment.Enrollment, error) {
 	const op = "storage.postgres.UnsibscribeFromEvent"
 
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	promoted, err := withdraw(ctx, tx, enrollmentId)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return promoted, nil
+}
+
+// withdraw does the work of UnsubscribeFromEvent within the transaction.
+func withdraw(ctx context.Context, tx pgx.Tx, enrollmentID uuid.UUID) ([]enrollment.Enrollment, error) {
+	var eventID uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT event_id FROM enrollments WHERE id = $1`, enrollmentID).Scan(&eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = lockEvent(ctx, tx, eventID); err != nil {
+		return nil, err
+	}
+
+	var status enrollment.Status
+	err = tx.QueryRow(ctx, `DELETE FROM enrollments WHERE id = $1 RETURNING status`, enrollmentID).Scan(&status)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != enrollment.StatusConfirmed {
+		return nil, nil
+	}
+
 	query := `
-	WITH deleted AS (
-		DELETE FROM enrollments
-		WHERE id = $1
-		RETURNING event_id
-	)
 	UPDATE events
 	SET available_seats = available_seats + 1
-	WHERE id IN (SELECT event_id FROM deleted)
-	AND has_unlimited_seats = FALSE;
-
+	WHERE id = $1
+	AND has_unlimited_seats = FALSE
 	`
 
-	_, err := s.conn.Exec(ctx, query, enrollmentId)
+	if _, err = tx.Exec(ctx, query, eventID); err != nil {
+		return nil, err
+	}
+
+	promoted, err := promoteWaitlist(ctx, tx, eventID)
 	if err != nil {
-		return fmt.Errorf("op: %s, err: %v", op, err)
+		return nil, err
 	}
 
-	return nil
+	if len(promoted) > 0 {
+		if err = scheduleReminders(ctx, tx, eventID, enrollmentIDs(promoted)); err != nil {
+			return nil, err
+		}
+	}
+
+	return promoted, nil
 }
 
 func (s *Storage) GetAllSubscriptions(ctx context.Context, eventId uuid.UUID) ([]enrollment.Enrollment, error) {
@@ -467,7 +1032,7 @@ func (s *Storage) GetAllSubscriptions(ctx context.Context, eventId uuid.UUID) ([
 	var enrollments []enrollment.Enrollment
 
 	query := `
-	SELECT id, created_at, user_email
+	SELECT id, created_at, user_id, status
 	FROM enrollments
 	WHERE event_id = $1
 	`
@@ -489,7 +1054,8 @@ func (s *Storage) GetAllSubscriptions(ctx context.Context, eventId uuid.UUID) ([
 		err = rows.Scan(
 			&idString,
 			&enrllmnt.CreatedAt,
-			&enrllmnt.UserEmail,
+			&enrllmnt.UserID,
+			&enrllmnt.Status,
 		)
 
 		if err != nil {
@@ -508,6 +1074,66 @@ func (s *Storage) GetAllSubscriptions(ctx context.Context, eventId uuid.UUID) ([
 	return enrollments, nil
 }
 
+// eventColumns are the columns of the event aliased as e, in the order
+// scanEvent reads them.
+const eventColumns = `e.id, e.title, e.type, e.date, e.total_seats, e.available_seats, e.creator_id,
+	ST_AsText(e.location), e.has_unlimited_seats, e.description, e.sequence,
+	e.ends_at, e.time_zone,
+	` + eventStatus + `, e.cancellation_reason, e.publish_at, e.series_id, e.recurrence_id,
+	(SELECT s.rrule FROM event_series s WHERE s.id = e.series_id),
+	ARRAY(SELECT t.name FROM event_tags et JOIN tags t ON t.id = et.tag_id WHERE et.event_id = e.id ORDER BY t.name)`
+
+// scanEvent reads a row of eventColumns, followed by the extra columns.
+func scanEvent(row pgx.Row, extra ...any) (event.Event, error) {
+	var ev event.Event
+	var locationWKT string
+	var hasUnlimitedSeats bool
+
+	dest := []any{
+		&ev.ID,
+		&ev.Title,
+		&ev.Type,
+		&ev.Date,
+		&ev.TotalSeats,
+		&ev.AvailableSeats,
+		&ev.CreatorID,
+		&locationWKT,
+		&hasUnlimitedSeats,
+		&ev.Description,
+		&ev.Sequence,
+		&ev.EndsAt,
+		&ev.TimeZone,
+		&ev.Status,
+		&ev.CancellationReason,
+		&ev.PublishAt,
+		&ev.SeriesID,
+		&ev.RecurrenceID,
+		&ev.RRule,
+		&ev.Tags,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return event.Event{}, err
+	}
+
+	ev.Date = ev.Date.In(ev.Zone())
+	ev.EndsAt = ev.EndsAt.In(ev.Zone())
+
+	if hasUnlimitedSeats {
+		ev.HasUnlimitedSeats = "true"
+	} else {
+		ev.HasUnlimitedSeats = "false"
+	}
+
+	ev.Location, err = parseWKT(locationWKT)
+	if err != nil {
+		return event.Event{}, fmt.Errorf("invalid location format: %v", err)
+	}
+
+	return ev, nil
+}
+
 func parseWKT(wkt string) (orb.Point, error) {
 	// fmt.Println(wkt)
 	parts := strings.Fields(strings.TrimPrefix(strings.TrimSuffix(wkt, ")"), "POINT("))
@@ -0,0 +1,383 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/user"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/paulmach/orb"
+)
+
+// testDatabaseEnv names the variable holding the connection string of a
+// database the tests may migrate and write to.
+const testDatabaseEnv = "SPDA_TEST_DATABASE_URL"
+
+// newTestStorage connects to the test database and applies the migrations.
+// The test is skipped when no database is configured.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	s := New(context.Background(), migrateTestDatabase(t))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// newTestStorageConns is newTestStorage with a pool of the given size, for
+// the tests that need many transactions running at once.
+func newTestStorageConns(t *testing.T, conns int32) *Storage {
+	t.Helper()
+
+	cfg, err := pgxpool.ParseConfig(migrateTestDatabase(t))
+	if err != nil {
+		t.Fatalf("parsing connection string: %v", err)
+	}
+	cfg.MaxConns = conns
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return &Storage{conn: pool}
+}
+
+// migrateTestDatabase applies the migrations to the test database and returns
+// its connection string, skipping the test when no database is configured.
+func migrateTestDatabase(t *testing.T) string {
+	t.Helper()
+
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	m, err := migrate.New("file://../../../migrations", dsn)
+	if err != nil {
+		t.Fatalf("initializing migrations: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	srcErr, dbErr := m.Close()
+	if srcErr != nil || dbErr != nil {
+		t.Fatalf("closing migrations: %v, %v", srcErr, dbErr)
+	}
+
+	return dsn
+}
+
+// insertTestUser adds a user with a unique email.
+func insertTestUser(t *testing.T, s *Storage) uuid.UUID {
+	t.Helper()
+
+	id, err := s.InsertUser(context.Background(), user.User{
+		Name:     "Test",
+		LastName: "User",
+		Email:    uuid.NewString() + "@example.com",
+		Password: "x",
+		Role:     user.RoleUser,
+		Locale:   "en",
+	})
+	if err != nil {
+		t.Fatalf("inserting user: %v", err)
+	}
+
+	return id
+}
+
+// insertTestEvent adds a published event of the user, a day from now. Zero
+// seats make it unlimited.
+func insertTestEvent(t *testing.T, s *Storage, creatorID uuid.UUID, seats int, mod func(*event.Event)) event.Event {
+	t.Helper()
+
+	ctx := context.Background()
+
+	typ := event.Type{Names: map[string]string{"en": "Test"}}
+	if err := s.InsertEventType(ctx, &typ); err != nil {
+		t.Fatalf("inserting event type: %v", err)
+	}
+
+	date := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	evt := event.Event{
+		Title:             "Test event",
+		Type:              typ.ID,
+		Date:              date,
+		EndsAt:            date.Add(time.Hour),
+		TimeZone:          event.DefaultTimeZone,
+		TotalSeats:        seats,
+		AvailableSeats:    seats,
+		CreatorID:         creatorID,
+		Location:          orb.Point{49.1, 55.8},
+		HasUnlimitedSeats: "false",
+		Status:            event.StatusPublished,
+	}
+	if seats == 0 {
+		evt.HasUnlimitedSeats = "true"
+	}
+	if mod != nil {
+		mod(&evt)
+	}
+
+	if err := s.InsertEvent(ctx, &evt); err != nil {
+		t.Fatalf("inserting event: %v", err)
+	}
+
+	return evt
+}
+
+func TestSubscribeToEventConcurrent(t *testing.T) {
+	const (
+		seats   = 5
+		members = 300
+		// enough connections for the enrollments to really overlap, while
+		// staying under the default max_connections of Postgres
+		conns = 64
+	)
+
+	s := newTestStorageConns(t, conns)
+	ctx := context.Background()
+
+	creator := insertTestUser(t, s)
+	evt := insertTestEvent(t, s, creator, seats, nil)
+
+	users := make([]uuid.UUID, members)
+	for i := range users {
+		users[i] = insertTestUser(t, s)
+	}
+
+	// the seats are sampled while the enrollments run, so a negative count
+	// that is later put right would still be seen
+	done := make(chan struct{})
+	minSeats := make(chan int)
+	go func() {
+		lowest := seats
+		for {
+			select {
+			case <-done:
+				minSeats <- lowest
+				return
+			default:
+			}
+
+			var available int
+			err := s.conn.QueryRow(ctx, `SELECT available_seats FROM events WHERE id = $1`, evt.ID).Scan(&available)
+			if err == nil && available < lowest {
+				lowest = available
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	results := make([]enrollment.Enrollment, members)
+	errs := make([]error, members)
+	start := make(chan struct{})
+
+	for i, userID := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = s.SubscribeToEvent(ctx, evt.ID, userID)
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+	close(done)
+
+	if lowest := <-minSeats; lowest < 0 {
+		t.Errorf("available seats went down to %d", lowest)
+	}
+
+	confirmed, waitlisted := 0, 0
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("enrollment %d: %v", i, err)
+		}
+
+		switch results[i].Status {
+		case enrollment.StatusConfirmed:
+			confirmed++
+		case enrollment.StatusWaitlist:
+			waitlisted++
+		default:
+			t.Errorf("enrollment %d has status %q", i, results[i].Status)
+		}
+	}
+
+	if confirmed != seats {
+		t.Errorf("confirmed %d enrollments, want %d", confirmed, seats)
+	}
+	if waitlisted != members-seats {
+		t.Errorf("waitlisted %d enrollments, want %d", waitlisted, members-seats)
+	}
+
+	var available, stored int
+	query := `
+	SELECT e.available_seats, COUNT(en.id) FILTER (WHERE en.status = 'confirmed')
+	FROM events e
+	LEFT JOIN enrollments en ON en.event_id = e.id
+	WHERE e.id = $1
+	GROUP BY e.id
+	`
+	if err := s.conn.QueryRow(ctx, query, evt.ID).Scan(&available, &stored); err != nil {
+		t.Fatalf("reading event: %v", err)
+	}
+
+	if available != 0 {
+		t.Errorf("available seats = %d, want 0", available)
+	}
+	if stored != seats {
+		t.Errorf("stored %d confirmed enrollments, want %d", stored, seats)
+	}
+}
+
+func TestRemindersAfterReschedule(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	creator := insertTestUser(t, s)
+	evt := insertTestEvent(t, s, creator, 0, func(e *event.Event) {
+		e.Date = time.Now().Add(48 * time.Hour).Truncate(time.Second)
+		e.EndsAt = e.Date.Add(time.Hour)
+	})
+
+	enrlmnt, err := s.SubscribeToEvent(ctx, evt.ID, insertTestUser(t, s))
+	if err != nil {
+		t.Fatalf("enrolling: %v", err)
+	}
+
+	// the day-before reminder goes out
+	query := `UPDATE scheduled_notifications SET sent_at = NOW() WHERE enrollment_id = $1`
+	if tag, err := s.conn.Exec(ctx, query, enrlmnt.Id); err != nil || tag.RowsAffected() != 1 {
+		t.Fatalf("marking the reminder sent: %v, %d rows", err, tag.RowsAffected())
+	}
+
+	// an unrelated edit keeps the sent reminder
+	evt.Title = "Renamed"
+	if _, _, err := s.UpdateEvent(ctx, evt); err != nil {
+		t.Fatalf("renaming event: %v", err)
+	}
+
+	var pending, sent int
+	query = `
+	SELECT COUNT(*) FILTER (WHERE sent_at IS NULL), COUNT(*) FILTER (WHERE sent_at IS NOT NULL)
+	FROM scheduled_notifications
+	WHERE enrollment_id = $1
+	`
+	if err := s.conn.QueryRow(ctx, query, enrlmnt.Id).Scan(&pending, &sent); err != nil {
+		t.Fatalf("reading reminders: %v", err)
+	}
+	if pending != 0 || sent != 1 {
+		t.Errorf("after renaming: %d pending and %d sent reminders, want 0 and 1", pending, sent)
+	}
+
+	evt.Date = evt.Date.Add(7 * 24 * time.Hour)
+	evt.EndsAt = evt.Date.Add(time.Hour)
+	if _, _, err := s.UpdateEvent(ctx, evt); err != nil {
+		t.Fatalf("moving event: %v", err)
+	}
+
+	// send_at has no time zone, so it is compared on the database side
+	var onTime bool
+	query = `
+	SELECT sn.send_at = ev.date - INTERVAL '1440 minutes'
+	FROM scheduled_notifications sn
+	JOIN enrollments e ON e.id = sn.enrollment_id
+	JOIN events ev ON ev.id = e.event_id
+	WHERE sn.enrollment_id = $1 AND sn.sent_at IS NULL
+	`
+	if err := s.conn.QueryRow(ctx, query, enrlmnt.Id).Scan(&onTime); err != nil {
+		t.Fatalf("reading the new reminder: %v", err)
+	}
+	if !onTime {
+		t.Error("new reminder is not due a day before the new date")
+	}
+}
+
+func TestGetAllEventsPagingTies(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	creator := insertTestUser(t, s)
+	date := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	near, far := orb.Point{49.1, 55.8}, orb.Point{37.6, 55.7}
+
+	// few distinct keys, so most pages end inside a run of equal keys
+	for i := range 11 {
+		insertTestEvent(t, s, creator, []int{0, 5, 5}[i%3], func(e *event.Event) {
+			e.Date = date.Add(time.Duration(i%2) * time.Hour)
+			e.EndsAt = e.Date.Add(time.Hour)
+			e.Location = []orb.Point{near, far}[i%2]
+		})
+	}
+
+	location := near
+
+	for _, sort := range []event.Sort{event.SortDate, event.SortSeats, event.SortDistance, event.SortCreatedAt} {
+		for _, desc := range []bool{false, true} {
+			f := event.Filter{CreatorID: &creator, Location: &location, Sort: sort, Desc: desc}
+
+			all, err := s.GetAllEvents(ctx, f)
+			if err != nil {
+				t.Fatalf("listing by %s: %v", sort, err)
+			}
+			if len(all.Events) != 11 {
+				t.Fatalf("listing by %s returned %d events, want 11", sort, len(all.Events))
+			}
+
+			var paged []uuid.UUID
+			f.Limit = 3
+			for pages := 0; ; pages++ {
+				if pages > 11 {
+					t.Fatalf("paging by %s does not end", sort)
+				}
+
+				page, err := s.GetAllEvents(ctx, f)
+				if err != nil {
+					t.Fatalf("paging by %s: %v", sort, err)
+				}
+				for _, evt := range page.Events {
+					paged = append(paged, evt.ID)
+				}
+				if page.NextCursor == nil {
+					break
+				}
+
+				// the cursor goes through its text form, as it does for clients
+				text, err := page.NextCursor.MarshalText()
+				if err != nil {
+					t.Fatalf("marshalling cursor: %v", err)
+				}
+				var cursor event.Cursor
+				if err := cursor.UnmarshalText(text); err != nil {
+					t.Fatalf("unmarshalling cursor: %v", err)
+				}
+				f.Cursor = &cursor
+			}
+
+			want := make([]uuid.UUID, 0, len(all.Events))
+			for _, evt := range all.Events {
+				want = append(want, evt.ID)
+			}
+
+			if !slices.Equal(paged, want) {
+				t.Errorf("paging by %s (desc %v) gave\n%v\nwant\n%v", sort, desc, paged, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,558 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/event"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const seriesColumns = `id, rrule, starts_at, exdates, template, generated_until, finished`
+
+func scanSeries(row pgx.Row) (event.Series, error) {
+	var series event.Series
+	err := row.Scan(
+		&series.ID,
+		&series.RRule,
+		&series.Start,
+		&series.ExDates,
+		&series.Template,
+		&series.GeneratedUntil,
+		&series.Finished,
+	)
+	return series, err
+}
+
+// InsertSeries stores the series and an occurrence for every start. The
+// occurrences are returned in order.
+func (s *Storage) InsertSeries(ctx context.Context, series *event.Series, starts []time.Time) ([]event.Event, error) {
+	const op = "storage.postgres.InsertSeries"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+	INSERT INTO event_series (creator_id, rrule, starts_at, exdates, template, generated_until, finished)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id
+	`
+
+	err = tx.QueryRow(ctx, query,
+		series.Template.CreatorID,
+		series.RRule,
+		series.Start,
+		series.ExDates,
+		series.Template,
+		series.GeneratedUntil,
+		series.Finished,
+	).Scan(&series.ID)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if _, err = insertOccurrences(ctx, tx, series.ID, series.Template, starts); err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `SELECT ` + eventColumns + ` FROM events e WHERE e.series_id = $1 ORDER BY e.date`
+
+	rows, err := tx.Query(ctx, query, series.ID)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	occurrences, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (event.Event, error) {
+		return scanEvent(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return occurrences, nil
+}
+
+// insertOccurrences copies the template, with its tags, once for every
+// start. Starts that already have an occurrence are skipped. The ids of the
+// new occurrences are returned.
+func insertOccurrences(ctx context.Context, tx pgx.Tx, seriesID uuid.UUID, template event.Event, starts []time.Time) ([]uuid.UUID, error) {
+	query := `
+	INSERT INTO events (title, type, date, total_seats, available_seats, creator_id, location,
+	                    has_unlimited_seats, description, status, publish_at, series_id, recurrence_id,
+	                    ends_at, time_zone)
+	SELECT $1::text, $2::int, t, $3::int, $3::int, $4::uuid, ST_GeomFromText($5::text, 4326),
+	       $6::boolean, $7::text, $8::text, $9::timestamptz, $10::uuid, t,
+	       t + make_interval(secs => $12), $13::text
+	FROM unnest($11::timestamptz[]) AS t
+	ON CONFLICT (series_id, recurrence_id) DO NOTHING
+	RETURNING id
+	`
+
+	rows, err := tx.Query(ctx, query,
+		template.Title,
+		template.Type,
+		template.TotalSeats,
+		template.CreatorID,
+		fmt.Sprintf("POINT(%f %f)", template.Location.Lon(), template.Location.Lat()),
+		template.HasUnlimitedSeats,
+		template.Description,
+		template.Status,
+		template.PublishAt,
+		seriesID,
+		starts,
+		template.Duration().Seconds(),
+		template.TimeZone,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+
+	if err = setTags(ctx, tx, ids, template.Tags); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *Storage) GetSeries(ctx context.Context, id uuid.UUID) (event.Series, error) {
+	const op = "storage.postgres.GetSeries"
+
+	query := `SELECT ` + seriesColumns + ` FROM event_series WHERE id = $1`
+
+	series, err := scanSeries(s.conn.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return event.Series{}, storage.ErrorEventNotFound
+		}
+		return event.Series{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return series, nil
+}
+
+// GetSeriesToExtend returns up to limit series that are generated only up to
+// a moment before until and have starts left.
+func (s *Storage) GetSeriesToExtend(ctx context.Context, until time.Time, limit int) ([]event.Series, error) {
+	const op = "storage.postgres.GetSeriesToExtend"
+
+	query := `
+	SELECT ` + seriesColumns + `
+	FROM event_series
+	WHERE NOT finished AND generated_until < $1
+	ORDER BY generated_until
+	LIMIT $2
+	`
+
+	rows, err := s.conn.Query(ctx, query, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	series, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (event.Series, error) {
+		return scanSeries(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return series, nil
+}
+
+// ExtendSeries adds the occurrences for the starts and moves the series on to
+// until. Users enrolled in the whole series are enrolled in the new
+// occurrences in the order they subscribed. Nothing happens if the series
+// has been extended or edited since it was read, the next run picks it up
+// again.
+func (s *Storage) ExtendSeries(ctx context.Context, series event.Series, starts []time.Time, until time.Time, finished bool) error {
+	const op = "storage.postgres.ExtendSeries"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query := `
+	UPDATE event_series
+	SET generated_until = $3, finished = $4
+	WHERE id = $1 AND generated_until = $2 AND NOT finished
+	RETURNING template
+	`
+
+	var template event.Event
+
+	err = tx.QueryRow(ctx, query, series.ID, series.GeneratedUntil, until, finished).Scan(&template)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	ids, err := insertOccurrences(ctx, tx, series.ID, template, starts)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `
+	INSERT INTO enrollments (user_id, event_id, status)
+	SELECT se.user_id, e.id,
+	       CASE WHEN e.has_unlimited_seats
+	              OR ROW_NUMBER() OVER (PARTITION BY e.id ORDER BY se.created_at) <= e.total_seats
+	            THEN 'confirmed' ELSE 'waitlist' END
+	FROM series_enrollments se
+	JOIN events e ON e.series_id = se.series_id
+	WHERE se.series_id = $1 AND e.id = ANY($2) AND e.status = 'published'
+	`
+
+	if _, err = tx.Exec(ctx, query, series.ID, ids); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query = `
+	UPDATE events e
+	SET available_seats = e.total_seats - (
+		SELECT COUNT(*) FROM enrollments en WHERE en.event_id = e.id AND en.status = 'confirmed'
+	)
+	WHERE e.id = ANY($1) AND NOT e.has_unlimited_seats
+	`
+
+	if _, err = tx.Exec(ctx, query, ids); err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	for _, id := range ids {
+		if err = scheduleReminders(ctx, tx, id, nil); err != nil {
+			return fmt.Errorf("op: %s, err: %v", op, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return nil
+}
+
+// seriesOf returns the series of the occurrence.
+func seriesOf(ctx context.Context, tx pgx.Tx, eventID uuid.UUID) (uuid.UUID, error) {
+	var seriesID *uuid.UUID
+
+	err := tx.QueryRow(ctx, `SELECT series_id FROM events WHERE id = $1`, eventID).Scan(&seriesID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, storage.ErrorEventNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	if seriesID == nil {
+		return uuid.Nil, storage.ErrorNotRecurring
+	}
+
+	return *seriesID, nil
+}
+
+// SubscribeToSeries enrolls the user in every open occurrence of the series
+// of the event, and in the occurrences created later. Each occurrence has
+// its own seats, the user is waitlisted where there are none left. The
+// enrollments made now are returned in the order of the occurrences.
+func (s *Storage) SubscribeToSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) ([]enrollment.Enrollment, error) {
+	const op = "storage.postgres.SubscribeToSeries"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	seriesID, err := seriesOf(ctx, tx, eventID)
+	if err != nil {
+		if errors.Is(err, storage.ErrorEventNotFound) || errors.Is(err, storage.ErrorNotRecurring) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query := `
+	INSERT INTO series_enrollments (series_id, user_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+
+	tag, err := tx.Exec(ctx, query, seriesID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, storage.ErrorAlreadyEnrolled
+	}
+
+	query = `
+	SELECT e.id
+	FROM events e
+	WHERE e.series_id = $1
+	AND ` + eventStatus + ` = 'published'
+	AND NOT EXISTS (SELECT 1 FROM enrollments en WHERE en.event_id = e.id AND en.user_id = $2)
+	ORDER BY e.date, e.id
+	`
+
+	rows, err := tx.Query(ctx, query, seriesID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	enrollments := make([]enrollment.Enrollment, 0, len(ids))
+
+	for _, id := range ids {
+		enrlmnt, err := enroll(ctx, tx, id, userID)
+		if err != nil {
+			return nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+		enrollments = append(enrollments, enrlmnt)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return enrollments, nil
+}
+
+// UnsubscribeFromSeries withdraws the user from every occurrence of the
+// series of the event that has not started yet, and from the occurrences
+// created later. The enrollments promoted into the freed seats are returned.
+func (s *Storage) UnsubscribeFromSeries(ctx context.Context, eventID uuid.UUID, userID uuid.UUID) ([]enrollment.Enrollment, error) {
+	const op = "storage.postgres.UnsubscribeFromSeries"
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	seriesID, err := seriesOf(ctx, tx, eventID)
+	if err != nil {
+		if errors.Is(err, storage.ErrorEventNotFound) || errors.Is(err, storage.ErrorNotRecurring) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM series_enrollments WHERE series_id = $1 AND user_id = $2`, seriesID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	query := `
+	SELECT en.id
+	FROM enrollments en
+	JOIN events e ON e.id = en.event_id
+	WHERE e.series_id = $1 AND en.user_id = $2 AND e.date > NOW()
+	ORDER BY e.date, e.id
+	`
+
+	rows, err := tx.Query(ctx, query, seriesID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if tag.RowsAffected() == 0 && len(ids) == 0 {
+		return nil, storage.ErrorNotEnrolled
+	}
+
+	var promoted []enrollment.Enrollment
+
+	for _, id := range ids {
+		p, err := withdraw(ctx, tx, id)
+		if err != nil {
+			return nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+		promoted = append(promoted, p...)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return promoted, nil
+}
+
+// UpdateFollowing applies the edit of the occurrence evt to it and to every
+// later occurrence of its series. Each of them is moved in time by shift,
+// the difference between the new and the old start of evt, and cancelled
+// ones are only moved. tail describes the series from evt on. When headRule
+// is set the series is split: the old series keeps the earlier occurrences
+// and gets headRule, tail becomes a new series with the same subscribers.
+// Otherwise the old series is replaced by tail. The occurrences before and
+// after the edit and the enrollments promoted from waitlists are returned.
+func (s *Storage) UpdateFollowing(ctx context.Context, evt event.Event, shift time.Duration, tail event.Series, headRule *string) ([]event.Revision, []enrollment.Enrollment, error) {
+	const op = "storage.postgres.UpdateFollowing"
+
+	if evt.SeriesID == nil || evt.RecurrenceID == nil {
+		return nil, nil, storage.ErrorNotRecurring
+	}
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var generatedUntil time.Time
+	var finished bool
+
+	// the lock keeps the series from being extended in the middle of the edit
+	query := `SELECT generated_until, finished FROM event_series WHERE id = $1 FOR UPDATE`
+	if err = tx.QueryRow(ctx, query, *evt.SeriesID).Scan(&generatedUntil, &finished); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, storage.ErrorEventNotFound
+		}
+		return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	tailID := *evt.SeriesID
+
+	if headRule != nil {
+		query = `UPDATE event_series SET rrule = $2, finished = TRUE WHERE id = $1`
+		if _, err = tx.Exec(ctx, query, *evt.SeriesID, *headRule); err != nil {
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		query = `
+		INSERT INTO event_series (creator_id, rrule, starts_at, exdates, template, generated_until, finished)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+		`
+
+		err = tx.QueryRow(ctx, query,
+			tail.Template.CreatorID,
+			tail.RRule,
+			tail.Start,
+			tail.ExDates,
+			tail.Template,
+			generatedUntil.Add(shift),
+			finished,
+		).Scan(&tailID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		query = `
+		INSERT INTO series_enrollments (series_id, user_id, created_at)
+		SELECT $2, user_id, created_at FROM series_enrollments WHERE series_id = $1
+		`
+
+		if _, err = tx.Exec(ctx, query, *evt.SeriesID, tailID); err != nil {
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+	} else {
+		query = `
+		UPDATE event_series
+		SET rrule = $2, starts_at = $3, exdates = $4, template = $5, generated_until = $6
+		WHERE id = $1
+		`
+
+		_, err = tx.Exec(ctx, query, tailID, tail.RRule, tail.Start, tail.ExDates, tail.Template, generatedUntil.Add(shift))
+		if err != nil {
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+	}
+
+	query = `
+	SELECT id FROM events
+	WHERE series_id = $1 AND recurrence_id >= $2
+	ORDER BY date, id
+	`
+
+	rows, err := tx.Query(ctx, query, *evt.SeriesID, *evt.RecurrenceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	var revisions []event.Revision
+	var promoted []enrollment.Enrollment
+
+	for _, id := range ids {
+		if err = lockEvent(ctx, tx, id); err != nil {
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		old, err := scanEvent(tx.QueryRow(ctx, `SELECT `+eventColumns+` FROM events e WHERE e.id = $1`, id))
+		if err != nil {
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		query = `
+		UPDATE events
+		SET series_id = $2, recurrence_id = recurrence_id + make_interval(secs => $3)
+		WHERE id = $1
+		`
+
+		if _, err = tx.Exec(ctx, query, id, tailID, shift.Seconds()); err != nil {
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		if old.Status == event.StatusCancelled {
+			if _, err = tx.Exec(ctx, `UPDATE events SET date = date + make_interval(secs => $2), ends_at = ends_at + make_interval(secs => $2) WHERE id = $1`, id, shift.Seconds()); err != nil {
+				return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+			}
+			continue
+		}
+
+		next := evt
+		next.ID = old.ID
+		next.CreatorID = old.CreatorID
+		next.Date = old.Date.Add(shift)
+
+		updated, p, err := updateEvent(ctx, tx, next)
+		if err != nil {
+			if errors.Is(err, storage.ErrorNotEnoughSeats) {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("op: %s, err: %v", op, err)
+		}
+
+		revisions = append(revisions, event.Revision{Old: old, New: updated})
+		promoted = append(promoted, p...)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("%s: failed to commit transaction: %v", op, err)
+	}
+
+	return revisions, promoted, nil
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/session"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (s *Storage) InsertSession(ctx context.Context, sess session.Session, refreshTokenHash string) (uuid.UUID, error) {
+	const op = "storage.postgres.InsertSession"
+
+	var id uuid.UUID
+
+	query := `
+	INSERT INTO sessions(user_id, refresh_token_hash, expires_at)
+	VALUES ($1, $2, $3)
+	RETURNING id
+	`
+
+	err := s.conn.QueryRow(ctx, query,
+		sess.UserID,
+		refreshTokenHash,
+		sess.ExpiresAt,
+	).Scan(&id)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return uuid.UUID{}, storage.ErrorNoUser
+			}
+		}
+
+		return uuid.UUID{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetSession(ctx context.Context, id uuid.UUID) (session.Session, error) {
+	const op = "storage.postgres.GetSession"
+
+	var sess session.Session
+
+	query := `
+	SELECT id, user_id, created_at, expires_at, revoked_at
+	FROM sessions
+	WHERE id = $1
+	`
+
+	err := s.conn.QueryRow(ctx, query, id).Scan(
+		&sess.ID,
+		&sess.UserID,
+		&sess.CreatedAt,
+		&sess.ExpiresAt,
+		&sess.RevokedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return session.Session{}, storage.ErrorSessionNotFound
+		}
+
+		return session.Session{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return sess, nil
+}
+
+// RotateSession replaces the refresh token of an active session. The update
+// is conditional on the old hash, so a refresh token can be used only once
+// even when several requests race with it.
+func (s *Storage) RotateSession(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (session.Session, error) {
+	const op = "storage.postgres.RotateSession"
+
+	var sess session.Session
+
+	query := `
+	UPDATE sessions
+	SET refresh_token_hash = $2,
+	refreshed_at = NOW(),
+	expires_at = $3
+	WHERE refresh_token_hash = $1
+	AND revoked_at IS NULL
+	AND expires_at > NOW()
+	RETURNING id, user_id, created_at, expires_at, revoked_at
+	`
+
+	err := s.conn.QueryRow(ctx, query, oldHash, newHash, expiresAt).Scan(
+		&sess.ID,
+		&sess.UserID,
+		&sess.CreatedAt,
+		&sess.ExpiresAt,
+		&sess.RevokedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return session.Session{}, storage.ErrorSessionNotFound
+		}
+
+		return session.Session{}, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return sess, nil
+}
+
+func (s *Storage) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.postgres.RevokeSession"
+
+	query := `
+	UPDATE sessions
+	SET revoked_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	cmdTag, err := s.conn.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrorSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	const op = "storage.postgres.RevokeAllSessions"
+
+	query := `
+	UPDATE sessions
+	SET revoked_at = NOW()
+	WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := s.conn.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, current uuid.UUID) error {
+	const op = "storage.postgres.RevokeOtherSessions"
+
+	query := `
+	UPDATE sessions
+	SET revoked_at = NOW()
+	WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+
+	_, err := s.conn.Exec(ctx, query, userID, current)
+	if err != nil {
+		return fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return nil
+}
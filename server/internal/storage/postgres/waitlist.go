@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/models/enrollment"
+	"github.com/Kazan-Strelnikova/SPDA/server/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// lockEvent takes the row lock on the event for the rest of the transaction.
+// Every change of seats or enrollment statuses goes through this lock.
+func lockEvent(ctx context.Context, tx pgx.Tx, eventID uuid.UUID) error {
+	var id uuid.UUID
+
+	err := tx.QueryRow(ctx, `SELECT id FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrorEventNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// promoteWaitlist moves the oldest waitlisted enrollments into the free seats
+// of the event. The caller must hold the lock on the event row.
+func promoteWaitlist(ctx context.Context, tx pgx.Tx, eventID uuid.UUID) ([]enrollment.Enrollment, error) {
+	var unlimited bool
+	var available int
+
+	query := `SELECT COALESCE(has_unlimited_seats, FALSE), available_seats FROM events WHERE id = $1`
+	if err := tx.QueryRow(ctx, query, eventID).Scan(&unlimited, &available); err != nil {
+		return nil, err
+	}
+
+	var limit *int
+	if !unlimited {
+		if available < 1 {
+			return nil, nil
+		}
+		limit = &available
+	}
+
+	query = `
+	UPDATE enrollments
+	SET status = 'confirmed'
+	WHERE id IN (
+		SELECT id FROM enrollments
+		WHERE event_id = $1 AND status = 'waitlist'
+		ORDER BY created_at, id
+		LIMIT $2
+	)
+	RETURNING id, created_at, user_id, event_id, status
+	`
+
+	rows, err := tx.Query(ctx, query, eventID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	promoted, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (enrollment.Enrollment, error) {
+		var enrlmnt enrollment.Enrollment
+		err := row.Scan(&enrlmnt.Id, &enrlmnt.CreatedAt, &enrlmnt.UserID, &enrlmnt.EventId, &enrlmnt.Status)
+		return enrlmnt, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(promoted) > 0 && !unlimited {
+		query = `UPDATE events SET available_seats = available_seats - $2 WHERE id = $1`
+		if _, err = tx.Exec(ctx, query, eventID, len(promoted)); err != nil {
+			return nil, err
+		}
+	}
+
+	return promoted, nil
+}
+
+// GetWaitlistPosition returns the 1-based place of the enrollment in the
+// waitlist of its event.
+func (s *Storage) GetWaitlistPosition(ctx context.Context, enrollmentID uuid.UUID) (int, error) {
+	const op = "storage.postgres.GetWaitlistPosition"
+
+	var position int
+
+	query := `
+	SELECT COUNT(*) + 1
+	FROM enrollments e
+	JOIN enrollments me ON me.id = $1
+	WHERE e.event_id = me.event_id
+	AND e.status = 'waitlist'
+	AND (e.created_at, e.id) < (me.created_at, me.id)
+	`
+
+	if err := s.conn.QueryRow(ctx, query, enrollmentID).Scan(&position); err != nil {
+		return 0, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return position, nil
+}
+
+// GetWaitlist returns the waitlist of the event in the order of promotion.
+func (s *Storage) GetWaitlist(ctx context.Context, eventID uuid.UUID) ([]enrollment.Attendee, error) {
+	const op = "storage.postgres.GetWaitlist"
+
+	query := `
+	SELECT e.id, u.id, u.name, u.last_name, u.email, e.status,
+		ROW_NUMBER() OVER (ORDER BY e.created_at, e.id), e.created_at
+	FROM enrollments e
+	JOIN users u ON u.id = e.user_id
+	WHERE e.event_id = $1 AND e.status = 'waitlist'
+	ORDER BY e.created_at, e.id
+	`
+
+	rows, err := s.conn.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	attendees, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (enrollment.Attendee, error) {
+		var a enrollment.Attendee
+		err := row.Scan(&a.EnrollmentID, &a.UserID, &a.Name, &a.LastName, &a.Email, &a.Status, &a.Position, &a.CreatedAt)
+		return a, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("op: %s, err: %v", op, err)
+	}
+
+	return attendees, nil
+}
@@ -3,8 +3,21 @@ package storage
 import "errors"
 
 var (
-	ErrorNoUser           = errors.New("no user with this email")
-	ErrorUserExists       = errors.New("user with this email already exists")
-	ErrorEventNotFound    = errors.New("no event with this id")
-	ErrorLocationNotFound = errors.New("no location in cache")
+	ErrorNoUser            = errors.New("no user with this email")
+	ErrorUserExists        = errors.New("user with this email already exists")
+	ErrorEventNotFound     = errors.New("no event with this id")
+	ErrorLocationNotFound  = errors.New("no location in cache")
+	ErrorSessionNotFound   = errors.New("no active session with this token")
+	ErrorResetNotFound     = errors.New("no active password reset with this token")
+	ErrorAlreadyEnrolled   = errors.New("user is already enrolled in the event")
+	ErrorNotEnoughSeats    = errors.New("more enrollments than seats on the event")
+	ErrorNotEnrolled       = errors.New("user is not enrolled in the event")
+	ErrorEmailNotFound     = errors.New("no failed email with this id")
+	ErrorFeedNotFound      = errors.New("no calendar feed with this token")
+	ErrorEventClosed       = errors.New("event does not take enrollments")
+	ErrorEventCancelled    = errors.New("event is cancelled")
+	ErrorNotDraft          = errors.New("event is not a draft")
+	ErrorNotRecurring      = errors.New("event is not part of a series")
+	ErrorEventTypeNotFound = errors.New("no event type with this id")
+	ErrorEventTypeInUse    = errors.New("event type is in use")
 )
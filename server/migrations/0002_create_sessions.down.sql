@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sessions_user_email;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
+
+-- Accounts created before verification was introduced are trusted as is
+UPDATE users SET verified_at = NOW() WHERE verified_at IS NULL;
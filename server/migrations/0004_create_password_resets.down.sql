@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_password_resets_user_email;
+DROP TABLE IF EXISTS password_resets;
@@ -0,0 +1,38 @@
+-- password_resets.user_id -> password_resets.user_email
+ALTER TABLE password_resets ADD COLUMN IF NOT EXISTS user_email TEXT;
+UPDATE password_resets p SET user_email = u.email FROM users u WHERE u.id = p.user_id;
+ALTER TABLE password_resets ALTER COLUMN user_email SET NOT NULL;
+DROP INDEX IF EXISTS idx_password_resets_user_id;
+ALTER TABLE password_resets DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE password_resets DROP COLUMN IF EXISTS user_id;
+ALTER TABLE password_resets ADD CONSTRAINT fk_user FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_email ON password_resets(user_email, created_at);
+
+-- sessions.user_id -> sessions.user_email
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_email TEXT;
+UPDATE sessions s SET user_email = u.email FROM users u WHERE u.id = s.user_id;
+ALTER TABLE sessions ALTER COLUMN user_email SET NOT NULL;
+DROP INDEX IF EXISTS idx_sessions_user_id;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_id;
+ALTER TABLE sessions ADD CONSTRAINT fk_user FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_sessions_user_email ON sessions(user_email);
+
+-- enrollments.user_id -> enrollments.user_email
+ALTER TABLE enrollments ADD COLUMN IF NOT EXISTS user_email TEXT;
+UPDATE enrollments en SET user_email = u.email FROM users u WHERE u.id = en.user_id;
+ALTER TABLE enrollments ALTER COLUMN user_email SET NOT NULL;
+ALTER TABLE enrollments DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE enrollments DROP CONSTRAINT IF EXISTS enrollments_user_id_event_id_key;
+ALTER TABLE enrollments DROP COLUMN IF EXISTS user_id;
+ALTER TABLE enrollments ADD CONSTRAINT fk_user FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE;
+ALTER TABLE enrollments ADD CONSTRAINT enrollments_user_email_event_id_key UNIQUE (user_email, event_id);
+
+-- events.creator_id -> events.creator_email
+ALTER TABLE events ADD COLUMN IF NOT EXISTS creator_email TEXT;
+UPDATE events e SET creator_email = u.email FROM users u WHERE u.id = e.creator_id;
+ALTER TABLE events ALTER COLUMN creator_email SET NOT NULL;
+DROP INDEX IF EXISTS idx_events_creator_id;
+ALTER TABLE events DROP CONSTRAINT IF EXISTS fk_creator;
+ALTER TABLE events DROP COLUMN IF EXISTS creator_id;
+ALTER TABLE events ADD CONSTRAINT fk_creator FOREIGN KEY (creator_email) REFERENCES users(email) ON DELETE CASCADE;
@@ -0,0 +1,40 @@
+-- Reference users by their id instead of the email, so that the email can change
+
+-- events.creator_email -> events.creator_id
+ALTER TABLE events ADD COLUMN IF NOT EXISTS creator_id UUID;
+UPDATE events e SET creator_id = u.id FROM users u WHERE u.email = e.creator_email;
+ALTER TABLE events ALTER COLUMN creator_id SET NOT NULL;
+ALTER TABLE events DROP CONSTRAINT IF EXISTS fk_creator;
+ALTER TABLE events DROP COLUMN IF EXISTS creator_email;
+ALTER TABLE events ADD CONSTRAINT fk_creator FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_events_creator_id ON events(creator_id);
+
+-- enrollments.user_email -> enrollments.user_id
+ALTER TABLE enrollments ADD COLUMN IF NOT EXISTS user_id UUID;
+UPDATE enrollments en SET user_id = u.id FROM users u WHERE u.email = en.user_email;
+ALTER TABLE enrollments ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE enrollments DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE enrollments DROP CONSTRAINT IF EXISTS enrollments_user_email_event_id_key;
+ALTER TABLE enrollments DROP COLUMN IF EXISTS user_email;
+ALTER TABLE enrollments ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE enrollments ADD CONSTRAINT enrollments_user_id_event_id_key UNIQUE (user_id, event_id);
+
+-- sessions.user_email -> sessions.user_id
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_id UUID;
+UPDATE sessions s SET user_id = u.id FROM users u WHERE u.email = s.user_email;
+ALTER TABLE sessions ALTER COLUMN user_id SET NOT NULL;
+DROP INDEX IF EXISTS idx_sessions_user_email;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_email;
+ALTER TABLE sessions ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- password_resets.user_email -> password_resets.user_id
+ALTER TABLE password_resets ADD COLUMN IF NOT EXISTS user_id UUID;
+UPDATE password_resets p SET user_id = u.id FROM users u WHERE u.email = p.user_email;
+ALTER TABLE password_resets ALTER COLUMN user_id SET NOT NULL;
+DROP INDEX IF EXISTS idx_password_resets_user_email;
+ALTER TABLE password_resets DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE password_resets DROP COLUMN IF EXISTS user_email;
+ALTER TABLE password_resets ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'organizer'
+    CHECK (role IN ('user', 'organizer', 'admin'));
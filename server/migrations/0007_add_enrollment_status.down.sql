@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_enrollments_waitlist;
+DELETE FROM enrollments WHERE status = 'waitlist';
+ALTER TABLE enrollments DROP COLUMN IF EXISTS status;
@@ -0,0 +1,5 @@
+ALTER TABLE enrollments ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'confirmed'
+    CHECK (status IN ('confirmed', 'waitlist'));
+
+CREATE INDEX IF NOT EXISTS idx_enrollments_waitlist ON enrollments(event_id, created_at, id)
+    WHERE status = 'waitlist';
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS scheduled_notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    enrollment_id UUID NOT NULL,
+    offset_minutes INT NOT NULL CHECK (offset_minutes > 0),
+    send_at TIMESTAMP NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    last_error TEXT,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_enrollment FOREIGN KEY (enrollment_id) REFERENCES enrollments(id) ON DELETE CASCADE,
+    UNIQUE (enrollment_id, offset_minutes) -- Ensures every reminder is sent at most once
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_notifications_due ON scheduled_notifications(send_at)
+    WHERE sent_at IS NULL;
+
+-- Reminders for enrollments made before the table existed
+INSERT INTO scheduled_notifications (enrollment_id, offset_minutes, send_at)
+SELECT e.id, 1440, ev.date - INTERVAL '1440 minutes'
+FROM enrollments e
+JOIN events ev ON ev.id = e.event_id
+WHERE e.status = 'confirmed' AND ev.date - INTERVAL '1440 minutes' > NOW()
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE enrollments DROP COLUMN IF EXISTS reminder_offsets;
+ALTER TABLE users DROP COLUMN IF EXISTS reminder_offsets;
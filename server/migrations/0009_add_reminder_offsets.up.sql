@@ -0,0 +1,5 @@
+-- Minutes before the event at which reminders are sent
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reminder_offsets INT[] NOT NULL DEFAULT '{1440}';
+
+-- Overrides the offsets of the user for one event, NULL means no override
+ALTER TABLE enrollments ADD COLUMN IF NOT EXISTS reminder_offsets INT[];
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS dead_letter_emails;
+DROP TABLE IF EXISTS outbound_emails;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS outbound_emails (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    html TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_emails_next_attempt ON outbound_emails(next_attempt_at);
+
+-- Emails that could not be delivered after all attempts
+CREATE TABLE IF NOT EXISTS dead_letter_emails (
+    id UUID PRIMARY KEY,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    html TEXT NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    failed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+ALTER TABLE dead_letter_emails DROP COLUMN IF EXISTS text;
+ALTER TABLE outbound_emails DROP COLUMN IF EXISTS text;
+
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'en' CHECK (locale IN ('en', 'ru'));
+
+-- Plain-text alternative of the letter
+ALTER TABLE outbound_emails ADD COLUMN IF NOT EXISTS text TEXT NOT NULL DEFAULT '';
+ALTER TABLE dead_letter_emails ADD COLUMN IF NOT EXISTS text TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE dead_letter_emails DROP COLUMN IF EXISTS attachments;
+ALTER TABLE outbound_emails DROP COLUMN IF EXISTS attachments;
+
+ALTER TABLE events DROP COLUMN IF EXISTS sequence;
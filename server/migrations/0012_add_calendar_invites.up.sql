@@ -0,0 +1,6 @@
+-- Grows with every change of the event, calendar clients ignore invites
+-- with a sequence they have already seen
+ALTER TABLE events ADD COLUMN IF NOT EXISTS sequence INT NOT NULL DEFAULT 0;
+
+ALTER TABLE outbound_emails ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE dead_letter_emails ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,10 @@
+-- Edits of events that enrolled users have not been told about yet. Edits
+-- made in quick succession share one row and end up in one letter.
+CREATE TABLE IF NOT EXISTS pending_event_changes (
+    event_id UUID PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+    old_event JSONB NOT NULL,
+    send_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_event_changes_send_at ON pending_event_changes(send_at);
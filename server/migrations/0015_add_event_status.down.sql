@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_events_status;
+
+ALTER TABLE events DROP COLUMN IF EXISTS cancellation_reason;
+ALTER TABLE events DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE events DROP COLUMN IF EXISTS status;
@@ -0,0 +1,8 @@
+-- Published events count as completed once they have started, that status
+-- is derived from the date and never stored
+ALTER TABLE events ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'published', 'cancelled', 'completed'));
+ALTER TABLE events ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS cancellation_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_events_status ON events(status);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_publish_at;
+
+ALTER TABLE events DROP COLUMN IF EXISTS publish_at;
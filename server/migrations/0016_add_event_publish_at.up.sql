@@ -0,0 +1,8 @@
+-- For drafts the moment they are published automatically, for published
+-- events the moment they were published
+ALTER TABLE events ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+
+UPDATE events SET publish_at = NOW() WHERE status <> 'draft' AND publish_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_publish_at ON events(publish_at)
+    WHERE status = 'draft';
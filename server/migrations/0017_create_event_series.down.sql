@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS series_enrollments;
+
+DROP INDEX IF EXISTS idx_events_occurrence;
+
+ALTER TABLE events DROP COLUMN IF EXISTS recurrence_id;
+ALTER TABLE events DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS event_series;
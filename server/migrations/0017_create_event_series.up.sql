@@ -0,0 +1,32 @@
+-- A series holds the recurrence rule of a recurring event. Its occurrences
+-- are ordinary events, created up to generated_until and extended as time
+-- goes by. New occurrences are copies of the template.
+CREATE TABLE IF NOT EXISTS event_series (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    creator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rrule TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    exdates TIMESTAMP[] NOT NULL DEFAULT '{}',
+    template JSONB NOT NULL,
+    generated_until TIMESTAMP NOT NULL,
+    finished BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_series_generated_until ON event_series(generated_until)
+    WHERE NOT finished;
+
+-- recurrence_id is the start the rule gave the occurrence, it stays the same
+-- when the occurrence is moved
+ALTER TABLE events ADD COLUMN IF NOT EXISTS series_id UUID REFERENCES event_series(id) ON DELETE SET NULL;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_id TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_occurrence ON events(series_id, recurrence_id);
+
+-- Users enrolled in the whole series are enrolled in new occurrences too
+CREATE TABLE IF NOT EXISTS series_enrollments (
+    series_id UUID NOT NULL REFERENCES event_series(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (series_id, user_id)
+);
@@ -0,0 +1,21 @@
+ALTER TABLE users DROP COLUMN IF EXISTS time_zone;
+
+DROP INDEX IF EXISTS idx_events_ends_at;
+DROP INDEX IF EXISTS idx_events_date;
+
+ALTER TABLE events DROP COLUMN IF EXISTS time_zone;
+ALTER TABLE events DROP CONSTRAINT IF EXISTS chk_events_ends_after_start;
+ALTER TABLE events DROP COLUMN IF EXISTS ends_at;
+
+ALTER TABLE event_series ADD COLUMN IF NOT EXISTS exdates_utc TIMESTAMP[] NOT NULL DEFAULT '{}';
+UPDATE event_series SET exdates_utc = ARRAY(SELECT d AT TIME ZONE 'UTC' FROM unnest(exdates) AS d);
+ALTER TABLE event_series DROP COLUMN exdates;
+ALTER TABLE event_series RENAME COLUMN exdates_utc TO exdates;
+
+ALTER TABLE event_series ALTER COLUMN generated_until TYPE TIMESTAMP USING generated_until AT TIME ZONE 'UTC';
+ALTER TABLE event_series ALTER COLUMN starts_at TYPE TIMESTAMP USING starts_at AT TIME ZONE 'UTC';
+
+ALTER TABLE scheduled_notifications ALTER COLUMN send_at TYPE TIMESTAMP USING send_at AT TIME ZONE 'UTC';
+ALTER TABLE events ALTER COLUMN recurrence_id TYPE TIMESTAMP USING recurrence_id AT TIME ZONE 'UTC';
+ALTER TABLE events ALTER COLUMN publish_at TYPE TIMESTAMP USING publish_at AT TIME ZONE 'UTC';
+ALTER TABLE events ALTER COLUMN date TYPE TIMESTAMP USING date AT TIME ZONE 'UTC';
@@ -0,0 +1,27 @@
+-- Event times are absolute moments. The stored values so far were written
+-- as UTC wall clock.
+ALTER TABLE events ALTER COLUMN date TYPE TIMESTAMPTZ USING date AT TIME ZONE 'UTC';
+ALTER TABLE events ALTER COLUMN publish_at TYPE TIMESTAMPTZ USING publish_at AT TIME ZONE 'UTC';
+ALTER TABLE events ALTER COLUMN recurrence_id TYPE TIMESTAMPTZ USING recurrence_id AT TIME ZONE 'UTC';
+ALTER TABLE scheduled_notifications ALTER COLUMN send_at TYPE TIMESTAMPTZ USING send_at AT TIME ZONE 'UTC';
+
+ALTER TABLE event_series ALTER COLUMN starts_at TYPE TIMESTAMPTZ USING starts_at AT TIME ZONE 'UTC';
+ALTER TABLE event_series ALTER COLUMN generated_until TYPE TIMESTAMPTZ USING generated_until AT TIME ZONE 'UTC';
+
+ALTER TABLE event_series ADD COLUMN IF NOT EXISTS exdates_tz TIMESTAMPTZ[] NOT NULL DEFAULT '{}';
+UPDATE event_series SET exdates_tz = ARRAY(SELECT d AT TIME ZONE 'UTC' FROM unnest(exdates) AS d);
+ALTER TABLE event_series DROP COLUMN exdates;
+ALTER TABLE event_series RENAME COLUMN exdates_tz TO exdates;
+
+-- Events that had no end last an hour. time_zone is an IANA name, dates in
+-- letters are written in it unless the recipient has a zone of their own.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS ends_at TIMESTAMPTZ;
+UPDATE events SET ends_at = date + INTERVAL '1 hour' WHERE ends_at IS NULL;
+ALTER TABLE events ALTER COLUMN ends_at SET NOT NULL;
+ALTER TABLE events ADD CONSTRAINT chk_events_ends_after_start CHECK (ends_at > date);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT 'UTC';
+
+CREATE INDEX IF NOT EXISTS idx_events_date ON events(date);
+CREATE INDEX IF NOT EXISTS idx_events_ends_at ON events(ends_at);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone TEXT;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_events_created_at_id;
+DROP INDEX IF EXISTS idx_events_date_id;
+
+ALTER TABLE events ALTER COLUMN created_at DROP NOT NULL;
@@ -0,0 +1,6 @@
+UPDATE events SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE events ALTER COLUMN created_at SET NOT NULL;
+
+-- keyset pagination orders by the sort key, then the id
+CREATE INDEX IF NOT EXISTS idx_events_date_id ON events(date, id);
+CREATE INDEX IF NOT EXISTS idx_events_created_at_id ON events(created_at, id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_search;
+
+ALTER TABLE events DROP COLUMN IF EXISTS search;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS event_tags;
+DROP TABLE IF EXISTS tags;
+
+DROP INDEX IF EXISTS idx_events_type;
+ALTER TABLE events DROP CONSTRAINT IF EXISTS fk_events_type;
+
+DROP TABLE IF EXISTS event_types;
@@ -0,0 +1,33 @@
+-- Event types are managed by admins. names maps locales to the name of the
+-- type in that language.
+CREATE TABLE IF NOT EXISTS event_types (
+    id SERIAL PRIMARY KEY,
+    names JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the types already in use get placeholder names for admins to replace
+INSERT INTO event_types (id, names)
+SELECT DISTINCT type, jsonb_build_object('en', 'Type ' || type, 'ru', 'Тип ' || type)
+FROM events
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('event_types', 'id'), COALESCE((SELECT MAX(id) FROM event_types), 0) + 1, false);
+
+ALTER TABLE events ADD CONSTRAINT fk_events_type FOREIGN KEY (type) REFERENCES event_types(id);
+
+CREATE INDEX IF NOT EXISTS idx_events_type ON events(type);
+
+-- Tags are free-form and shared between events
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS event_tags (
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_tags_tag_id ON event_tags(tag_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_password_reset_requests_created_at;
+DROP TABLE IF EXISTS password_reset_requests;
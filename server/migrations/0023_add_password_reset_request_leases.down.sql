@@ -0,0 +1 @@
+ALTER TABLE password_reset_requests DROP COLUMN IF EXISTS claimed_until;
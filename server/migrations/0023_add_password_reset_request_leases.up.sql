@@ -0,0 +1,3 @@
+-- A claimed request stays in the table until its letter is queued, so it is
+-- handled again if the replica that claimed it fails.
+ALTER TABLE password_reset_requests ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP;